@@ -1,6 +1,13 @@
 // The dispel command generates source code based on a JSON Hyper-Schema for quickly building REST APIs in Go.
 //
 // It requires a unique argument, SCHEMA, which is the path to the JSON Hyper-Schema.
+// Schemas written in YAML are read from the files with the .yaml or .yml extension.
+// The schema documents it references with $ref are loaded relative to its directory, in JSON or YAML too.
+//
+// The x-go-name keyword overrides the name of the Go type of a schema, of the struct field of a property,
+// and of the handler func of a link.
+//
+// The errors found in the schema are all reported, one per line, with their file, line and column, and the JSON pointer of the offending node.
 //
 // It is best used in conjunction with go generate, by making use of $GOPACKAGE and $GOFILE envvars.
 //
@@ -61,6 +68,42 @@
 // The -o flag is only useful when -f is specified. It specifies a path where to write the output from -f.
 // By default, its value is -, which means it writes to STDOUT.
 //
+// The -tm flag maps formats and $refs to the Go types of their values, with a comma-separated list of key=type pairs.
+// The types are fully-qualified, and the packages they belong to are imported by the generated code. For example:
+//
+//     -tm uuid=github.com/google/uuid.UUID,#/definitions/price=github.com/shopspring/decimal.Decimal
+//
+//
+// The -in flag sets the initialisms written in all caps in the generated identifiers, with a comma-separated list.
+// By default, they're those golint knows, like ID, URL, HTTP, JSON or UUID: a property server_id generates a field ServerID.
+// The JSON names are unchanged. If set to the special value none, no word is an initialism. For example:
+//
+//     -in ID,URL,HTTP,JSON,UUID,SKU
+//
+//
+// The -sf flag sorts the fields of the generated structs by name.
+// By default, they're in the order of the properties in the JSON Schema.
+//
+// Lint
+//
+// The lint subcommand, dispel lint [-fail] SCHEMA, reports the features of the schema which dispel ignores or which are likely mistakes,
+// one per line, with the file, line and column, and the JSON pointer where they occur:
+//
+//  * the keywords which have no effect on the generated code, like not or dependencies, and the unknown ones (x- extensions excepted)
+//  * the $refs which can't be resolved, and the $ref cycles
+//  * the oneOf with an integer and a number variant, which reject integers
+//  * the required properties whose default value is never applied
+//  * the links without rel, and the links whose method isn't one of GET, HEAD, POST, PUT, PATCH, DELETE or OPTIONS
+//  * the href variables matching no property nor definition
+//  * the definitions which are never referenced, and have no links
+//  * the request and response bodies whose media type isn't JSON, and whose schema is therefore ignored
+//
+// For example:
+//
+//     api.json:12:9: #/definitions/spell/links/0: link has no rel
+//
+// The -fail flag makes dispel lint exit with a nonzero status if it reports any issue, which is useful in CI.
+//
 // The context passed to the template is the type Context.
 //
 // Generator Context
@@ -80,9 +123,9 @@
 //
 //  * tolower                   : calls strings.ToLower
 //  * capitalize                : uppercase the first rune of a string
-//  * symbolName                : uppercase each rune following one of ".- ", then uppercase the first rune 
+//  * symbolName                : the Go identifier of a name, built by the Namer of the SchemaParser; by default, capitalize the words delimited by one of ".-_ " or a change of case, and write the initialisms in all caps
 //  * hasItem                   : takes 2 arguments: ([]string, string); returns true if string is one of the elements of []string
-//  * handlerFuncName           : the handler func name for a route method and name
+//  * handlerFuncName           : the handler func name for a route method and name, or the x-go-name of its link
 //  * allHandlerFuncsImplemented: returns true if all handler funcs are implemented in the target package
 //  * varname                   : creates a short variable name from a type. e.g MyLongType would return mlt
 //  * typeImports               : returns a slice of imports required by the generated types
@@ -91,6 +134,20 @@
 //  * printTypeName             : prints the name of the Go type for a JSONType
 //  * printSmartDerefType       : is like printTypeName, but if the argument is a JSONObject, it return *TheType instead of TheType.
 //  * routesForType             : returns a list of routes in which the specified type is involved.
+//  * routeParamGoType          : returns the Go type of a route param: int, float64, bool, time.Time, an enum type, or string
+//  * printRouteParamParse      : prints the statements getting a route param in a handler, and converting it to its Go type
+//  * printRouteParamFormat     : takes 2 arguments: (RouteParam, string); prints the expression formatting the Go expression string as a route param value
+//  * handlersImports           : returns a slice of imports required by the generated handlers
+//  * routesImports             : returns a slice of imports required by the generated routes
+//  * handlerFuncsImports       : returns a slice of imports required by the generated handler funcs
+//  * queryTypes                : returns the types of the query string parameters of the routes
+//  * printQueryDecodeFunc      : prints the decode() method setting the fields of a query type from url.Values
+//  * printQueryValuesFunc      : prints the Values() method encoding a query type to url.Values
+//  * printTypeDescription      : prints the description of the schema of a type as a paragraph of its doc comment
+//  * printLinkDoc              : prints the title and description of a link as paragraphs of a doc comment
+//  * printResourceLinksDoc     : prints the lines of a doc comment listing the methods of a resource route with their link title
+//  * typeNeedsDefaults         : returns true if an applyDefaults() method is generated for a type
+//  * printApplyDefaultsFunc    : prints the applyDefaults() method setting the absent fields of a type to their default value
 //
 // For more information, see the documentation of the github.com/vincent-petithory/dispel package's Context type.
 package main
@@ -1,3 +1,3 @@
 package main
 
-var helptext = "The dispel command generates source code based on a JSON Hyper-Schema for quickly building REST APIs in Go.\n\nIt requires a unique argument, SCHEMA, which is the path to the JSON Hyper-Schema.\n\nIt is best used in conjunction with go generate, by making use of $GOPACKAGE and $GOFILE envvars.\n\nFlags\n\nThe --version flag makes dispel to print the API version of its generated code, and exits. See the Version constant in the github.com/vincent-petithory/dispel package for its meaning.\n\nThe -v flag makes dispel more verbose about what the entities it discovers while parsing the json schema.\n\nThe -t flag specifies which generator to execute, with a comma-separated list of generator names.\nThe names must be in the following list:\n\n    handlerfuncs\n    handlers\n    routes\n    types\n\n\nIf empty (the default), none is executed. If set to the special value all, all known generators are executed.\ndispel will write a file in the package dir (see -pp flag) for each name provided with a filename using the pattern {prefix}{name}.go, where prefix is defined by the -p flag.\n\nThe -d flag specifies which default implementations provided by dispel to execute,\nlike -t, using a comma-separated list of default implementation names.\nThe names must be in the following list:\n\n    defaults_codec\n    defaults_mux\n    methodhandler\n    methodhandler_test\n\n\nIf empty (the default), none is executed. If set to the special value all, all default implementations are executed.\ndispel will write a file in the package dir (see -pp flag) for each default implementation\nwith a filename using the pattern {impl-name}.go\n\nThe -p flag specifies which prefix to use for each generated file. By default, it is set to 'dispel_'.\nThis doesn't apply to default implementations, which have fixed names.\n\nThe -hrt flag specifies the Go type in the target package which\nwill be the receiver for the handler functions dispel generates.\nFor example, with a value of *AppHandlers, dispel will generate something like:\n\n    func (ah *AppHandlers) getUsers(w http.ResponseWriter, r *http.Request, ....\n\n\nThe -pp flag specifies which package dir to generate and analyze code into.\nIt is mandatory to set this flag if dispel is not invoked with go:generate.\nIf set when dispel is invoked with go:generate, it overrides the package path resolved from $GOFILE.\n\nThe -pn flag specifies the package name of the code generated by dispel.\nIt is mandatory to set a value if not invoked with go:generate.\nIf set when dispel is invoked with go:generate, it overrides the value of $GOPACKAGE.\n\nThe -f flag specifies the path to a Go template file which accepts the Context type detailed below.\nIf the value is -, then the template is read from STDIN.\nIf set, then -t and -d flags are ignored: only this template is executed. The result is printed to what the -o flag is set to, which by default is STDOUT.\n\nThe -o flag is only useful when -f is specified. It specifies a path where to write the output from -f.\nBy default, its value is -, which means it writes to STDOUT.\n\nThe context passed to the template is the type Context.\n\nGenerator Context\n\n    // Context represents the context passed to a Generator.\n    type Context struct {\n    	Schema              *SchemaParser // the SchemaParser which parsed the json schema\n    	Prgm                string        // name of the program generating the source\n    	PkgName             string        // package name for which source code is generated\n    	Routes              Routes        // routes parsed by the SchemaParser\n    	HandlerReceiverType string        // type which acts as the receiver of the handler funcs.\n    	ExistingHandlers    []string      // list of existing handler funcs in the target package, with HandlerReceiverType as the receiver\n    	ExistingTypes       []string      // list of existing types in the target package.\n    }\n\nThe template has those functions available:\n\n * tolower                   : calls strings.ToLower\n * capitalize                : uppercase the first rune of a string\n * symbolName                : uppercase each rune following one of \".- \", then uppercase the first rune \n * hasItem                   : takes 2 arguments: ([]string, string); returns true if string is one of the elements of []string\n * handlerFuncName           : the handler func name for a route method and name\n * allHandlerFuncsImplemented: returns true if all handler funcs are implemented in the target package\n * varname                   : creates a short variable name from a type. e.g MyLongType would return mlt\n * typeImports               : returns a slice of imports required by the generated types\n * printTypeDef              : prints a valid Go type from a JSONType\n * typeNeedsAddr             : returns true if it is needed to get the addr of a type when used as an argument of a func\n * printTypeName             : prints the name of the Go type for a JSONType\n * printSmartDerefType       : is like printTypeName, but if the argument is a JSONObject, it return *TheType instead of TheType.\n * routesForType             : returns a list of routes in which the specified type is involved.\n\nFor more information, see the documentation of the github.com/vincent-petithory/dispel package's Context type.\n"
+var helptext = "The dispel command generates source code based on a JSON Hyper-Schema for quickly building REST APIs in Go.\n\nIt requires a unique argument, SCHEMA, which is the path to the JSON Hyper-Schema.\nSchemas written in YAML are read from the files with the .yaml or .yml extension.\nThe schema documents it references with $ref are loaded relative to its directory, in JSON or YAML too.\n\nThe x-go-name keyword overrides the name of the Go type of a schema, of the struct field of a property,\nand of the handler func of a link.\n\nThe errors found in the schema are all reported, one per line, with their file, line and column, and the JSON pointer of the offending node.\n\nIt is best used in conjunction with go generate, by making use of $GOPACKAGE and $GOFILE envvars.\n\nFlags\n\nThe --version flag makes dispel to print the API version of its generated code, and exits. See the Version constant in the github.com/vincent-petithory/dispel package for its meaning.\n\nThe -v flag makes dispel more verbose about what the entities it discovers while parsing the json schema.\n\nThe -t flag specifies which generator to execute, with a comma-separated list of generator names.\nThe names must be in the following list:\n\n    handlerfuncs\n    handlers\n    routes\n    types\n\n\nIf empty (the default), none is executed. If set to the special value all, all known generators are executed.\ndispel will write a file in the package dir (see -pp flag) for each name provided with a filename using the pattern {prefix}{name}.go, where prefix is defined by the -p flag.\n\nThe -d flag specifies which default implementations provided by dispel to execute,\nlike -t, using a comma-separated list of default implementation names.\nThe names must be in the following list:\n\n    defaults_codec\n    defaults_mux\n    methodhandler\n    methodhandler_test\n\n\nIf empty (the default), none is executed. If set to the special value all, all default implementations are executed.\ndispel will write a file in the package dir (see -pp flag) for each default implementation\nwith a filename using the pattern {impl-name}.go\n\nThe -p flag specifies which prefix to use for each generated file. By default, it is set to 'dispel_'.\nThis doesn't apply to default implementations, which have fixed names.\n\nThe -hrt flag specifies the Go type in the target package which\nwill be the receiver for the handler functions dispel generates.\nFor example, with a value of *AppHandlers, dispel will generate something like:\n\n    func (ah *AppHandlers) getUsers(w http.ResponseWriter, r *http.Request, ....\n\n\nThe -pp flag specifies which package dir to generate and analyze code into.\nIt is mandatory to set this flag if dispel is not invoked with go:generate.\nIf set when dispel is invoked with go:generate, it overrides the package path resolved from $GOFILE.\n\nThe -pn flag specifies the package name of the code generated by dispel.\nIt is mandatory to set a value if not invoked with go:generate.\nIf set when dispel is invoked with go:generate, it overrides the value of $GOPACKAGE.\n\nThe -f flag specifies the path to a Go template file which accepts the Context type detailed below.\nIf the value is -, then the template is read from STDIN.\nIf set, then -t and -d flags are ignored: only this template is executed. The result is printed to what the -o flag is set to, which by default is STDOUT.\n\nThe -o flag is only useful when -f is specified. It specifies a path where to write the output from -f.\nBy default, its value is -, which means it writes to STDOUT.\n\nThe -tm flag maps formats and $refs to the Go types of their values, with a comma-separated list of key=type pairs.\nThe types are fully-qualified, and the packages they belong to are imported by the generated code. For example:\n\n    -tm uuid=github.com/google/uuid.UUID,#/definitions/price=github.com/shopspring/decimal.Decimal\n\n\nThe -in flag sets the initialisms written in all caps in the generated identifiers, with a comma-separated list.\nBy default, they're those golint knows, like ID, URL, HTTP, JSON or UUID: a property server_id generates a field ServerID.\nThe JSON names are unchanged. If set to the special value none, no word is an initialism. For example:\n\n    -in ID,URL,HTTP,JSON,UUID,SKU\n\n\nThe -sf flag sorts the fields of the generated structs by name.\nBy default, they're in the order of the properties in the JSON Schema.\n\nLint\n\nThe lint subcommand, dispel lint [-fail] SCHEMA, reports the features of the schema which dispel ignores or which are likely mistakes,\none per line, with the file, line and column, and the JSON pointer where they occur:\n\n * the keywords which have no effect on the generated code, like not or dependencies, and the unknown ones (x- extensions excepted)\n * the $refs which can't be resolved, and the $ref cycles\n * the oneOf with an integer and a number variant, which reject integers\n * the required properties whose default value is never applied\n * the links without rel, and the links whose method isn't one of GET, HEAD, POST, PUT, PATCH, DELETE or OPTIONS\n * the href variables matching no property nor definition\n * the definitions which are never referenced, and have no links\n * the request and response bodies whose media type isn't JSON, and whose schema is therefore ignored\n\nFor example:\n\n    api.json:12:9: #/definitions/spell/links/0: link has no rel\n\nThe -fail flag makes dispel lint exit with a nonzero status if it reports any issue, which is useful in CI.\n\nThe context passed to the template is the type Context.\n\nGenerator Context\n\n    // Context represents the context passed to a Generator.\n    type Context struct {\n    	Schema              *SchemaParser // the SchemaParser which parsed the json schema\n    	Prgm                string        // name of the program generating the source\n    	PkgName             string        // package name for which source code is generated\n    	Routes              Routes        // routes parsed by the SchemaParser\n    	HandlerReceiverType string        // type which acts as the receiver of the handler funcs.\n    	ExistingHandlers    []string      // list of existing handler funcs in the target package, with HandlerReceiverType as the receiver\n    	ExistingTypes       []string      // list of existing types in the target package.\n    }\n\nThe template has those functions available:\n\n * tolower                   : calls strings.ToLower\n * capitalize                : uppercase the first rune of a string\n * symbolName                : the Go identifier of a name, built by the Namer of the SchemaParser; by default, capitalize the words delimited by one of \".-_ \" or a change of case, and write the initialisms in all caps\n * hasItem                   : takes 2 arguments: ([]string, string); returns true if string is one of the elements of []string\n * handlerFuncName           : the handler func name for a route method and name, or the x-go-name of its link\n * allHandlerFuncsImplemented: returns true if all handler funcs are implemented in the target package\n * varname                   : creates a short variable name from a type. e.g MyLongType would return mlt\n * typeImports               : returns a slice of imports required by the generated types\n * printTypeDef              : prints a valid Go type from a JSONType\n * typeNeedsAddr             : returns true if it is needed to get the addr of a type when used as an argument of a func\n * printTypeName             : prints the name of the Go type for a JSONType\n * printSmartDerefType       : is like printTypeName, but if the argument is a JSONObject, it return *TheType instead of TheType.\n * routesForType             : returns a list of routes in which the specified type is involved.\n * routeParamGoType          : returns the Go type of a route param: int, float64, bool, time.Time, an enum type, or string\n * printRouteParamParse      : prints the statements getting a route param in a handler, and converting it to its Go type\n * printRouteParamFormat     : takes 2 arguments: (RouteParam, string); prints the expression formatting the Go expression string as a route param value\n * handlersImports           : returns a slice of imports required by the generated handlers\n * routesImports             : returns a slice of imports required by the generated routes\n * handlerFuncsImports       : returns a slice of imports required by the generated handler funcs\n * queryTypes                : returns the types of the query string parameters of the routes\n * printQueryDecodeFunc      : prints the decode() method setting the fields of a query type from url.Values\n * printQueryValuesFunc      : prints the Values() method encoding a query type to url.Values\n * printTypeDescription      : prints the description of the schema of a type as a paragraph of its doc comment\n * printLinkDoc              : prints the title and description of a link as paragraphs of a doc comment\n * printResourceLinksDoc     : prints the lines of a doc comment listing the methods of a resource route with their link title\n * typeNeedsDefaults         : returns true if an applyDefaults() method is generated for a type\n * printApplyDefaultsFunc    : prints the applyDefaults() method setting the absent fields of a type to their default value\n\nFor more information, see the documentation of the github.com/vincent-petithory/dispel package's Context type.\n"
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// lint runs the lint subcommand with the arguments args, and returns the exit code of the program.
+//
+// It prints the issues found in the schema, prefixed with their location and their JSON pointer.
+// The exit code is 0, unless the -fail flag is set and issues were found.
+func lint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	var failOnIssues bool
+	fs.BoolVar(&failOnIssues, "fail", false, "")
+	fs.Usage = flag.Usage
+	_ = fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		log.Fatal("no jsonschema file provided")
+	}
+	schemaParser, err := NewSchemaParser(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if verbose {
+		schemaParser.Log = log.New(os.Stdout, "dispel> ", 0)
+	}
+
+	issues := schemaParser.Lint()
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if failOnIssues && len(issues) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -2,13 +2,13 @@ package main
 
 import (
 	"bytes"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"go/format"
 	"io"
 	"io/ioutil"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -25,6 +25,9 @@ var (
 	pkgname             string
 	altFormatPath       string
 	altFormatOutPath    string
+	goTypeList          string
+	initialismList      string
+	sortFields          bool
 	verbose             bool
 	showVersion         bool
 )
@@ -41,31 +44,38 @@ func init() {
 	flag.StringVar(&pkgname, "pn", "", "")
 	flag.StringVar(&altFormatPath, "f", "", "")
 	flag.StringVar(&altFormatOutPath, "o", "-", "")
+	flag.StringVar(&goTypeList, "tm", "", "")
+	flag.StringVar(&initialismList, "in", "", "")
+	flag.BoolVar(&sortFields, "sf", false, "")
 	flag.BoolVar(&verbose, "v", false, "")
 	flag.BoolVar(&showVersion, "version", false, "")
 	flag.Usage = func() {
-		fmt.Fprintln(os.Stderr, "usage: dispel [--version] [-t names] [-d names] [-p prefix] [-hrt typename] [-pp packagepath] [-pn packagename] [-f path] [-o path] [-v] SCHEMA")
+		fmt.Fprintln(os.Stderr, "usage: dispel [--version] [-t names] [-d names] [-p prefix] [-hrt typename] [-pp packagepath] [-pn packagename] [-f path] [-o path] [-tm mappings] [-in initialisms] [-sf] [-v] SCHEMA")
+		fmt.Fprintln(os.Stderr, "       dispel lint [-fail] SCHEMA")
 		fmt.Fprintln(os.Stderr)
 		fmt.Fprint(os.Stderr, helptext)
 	}
 }
 
-// NewSchemaParser creates a new SchemaParser for the json schema at path.
+// NewSchemaParser creates a new SchemaParser for the json schema at path, a JSON or YAML file.
+//
+// The documents it references are loaded relative to its directory.
+// If the schema has an absolute id (or $id, depending on its draft), the URIs in the same "directory" are loaded from there too.
 func NewSchemaParser(path string) (*dispel.SchemaParser, error) {
-	f, err := os.Open(path)
+	schema, err := dispel.ParseSchemaFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		_ = f.Close()
-	}()
 
-	var schema dispel.Schema
-	if err := json.NewDecoder(f).Decode(&schema); err != nil {
-		return nil, err
+	refLoader := &dispel.FileRefLoader{Dir: filepath.Dir(path)}
+	id := schema.DocumentID()
+	if u, err := url.Parse(id); err == nil && u.IsAbs() {
+		refLoader.BaseURI = id[:strings.LastIndex(id, "/")+1]
 	}
-
-	return &dispel.SchemaParser{RootSchema: &schema}, nil
+	return &dispel.SchemaParser{
+		RootSchema: schema,
+		RefLoader:  refLoader,
+	}, nil
 }
 
 func main() {
@@ -79,6 +89,10 @@ func main() {
 		return
 	}
 
+	if flag.Arg(0) == "lint" {
+		os.Exit(lint(flag.Args()[1:]))
+	}
+
 	// Check envvars from go:generate are set
 	var pkgAbsPath string
 	switch {
@@ -138,6 +152,31 @@ func main() {
 	if verbose {
 		schemaParser.Log = log.New(os.Stdout, "dispel> ", 0)
 	}
+	schemaParser.SortFields = sortFields
+	schemaParser.CollectErrors = true
+	if goTypeList != "" {
+		schemaParser.GoTypes = make(map[string]string)
+		for _, mapping := range strings.Split(goTypeList, ",") {
+			kv := strings.SplitN(mapping, "=", 2)
+			if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+				log.Fatalf("invalid type mapping %q, expected format=type or $ref=type", mapping)
+			}
+			schemaParser.GoTypes[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	switch initialismList {
+	case "":
+	case "none":
+		schemaParser.Namer = dispel.DefaultNamer{Initialisms: []string{}}
+	default:
+		var initialisms []string
+		for _, initialism := range strings.Split(initialismList, ",") {
+			if initialism = strings.TrimSpace(initialism); initialism != "" {
+				initialisms = append(initialisms, initialism)
+			}
+		}
+		schemaParser.Namer = dispel.DefaultNamer{Initialisms: initialisms}
+	}
 
 	// Create a dispel bundle  using the parser
 	bundle, err := dispel.NewBundle(schemaParser)
@@ -185,13 +224,15 @@ func main() {
 
 	// Parse the routes in the schema
 	routes, err := schemaParser.ParseRoutes()
-	if err != nil {
-		switch t := err.(type) {
-		case dispel.InvalidSchemaError:
-			log.Fatalf("Schema: %#v\nMsg: %s", t.Schema, t.Msg)
-		default:
-			log.Fatal(err)
+	if errs, ok := err.(dispel.SchemaErrors); ok {
+		// One line per error, so that editors can jump to each of them
+		for _, err := range errs {
+			log.Print(err)
 		}
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// Prepare context for template
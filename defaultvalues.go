@@ -0,0 +1,346 @@
+package dispel
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// errUnsupportedDefault is returned by checkDefault for the types whose default value isn't applied,
+// like objects, maps and unions.
+var errUnsupportedDefault = errors.New("unsupported default value")
+
+// checkDefault returns an error if the default value v doesn't match the type typ.
+func (sp *SchemaParser) checkDefault(typ JSONType, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	switch t := sp.ResolveType(nonNullType(typ)).(type) {
+	case JSONString:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%v is not a string", v)
+		}
+		if t.Format == "byte" {
+			if _, err := base64.StdEncoding.DecodeString(s); err != nil {
+				return fmt.Errorf("%q is not base64 encoded: %v", s, err)
+			}
+		}
+	case JSONEnum:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%v is not a string", v)
+		}
+		for _, value := range t.Values {
+			if s == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of the values of the enum", s)
+	case JSONDateTime:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%v is not a string", v)
+		}
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return fmt.Errorf("%q is not a date-time: %v", s, err)
+		}
+	case JSONBoolean:
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%v is not a boolean", v)
+		}
+	case JSONInteger:
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) {
+			return fmt.Errorf("%v is not an integer", v)
+		}
+		if t.Format == "int32" && (f < math.MinInt32 || f > math.MaxInt32) {
+			return fmt.Errorf("%v overflows an int32", v)
+		}
+	case JSONNumber:
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%v is not a number", v)
+		}
+	case JSONArray:
+		a, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%v is not an array", v)
+		}
+		for i, item := range a {
+			if item == nil {
+				return fmt.Errorf("item %d is null", i)
+			}
+			if err := sp.checkDefault(t.Items, item); err != nil {
+				return fmt.Errorf("item %d: %v", i, err)
+			}
+		}
+	default:
+		return errUnsupportedDefault
+	}
+	return nil
+}
+
+// checkDefaultConstraints returns an error if the default value v, of type typ, doesn't satisfy the constraints c,
+// as they're checked by the Validate() methods: a default value which doesn't would make them reject
+// every value where it's absent.
+func (sp *SchemaParser) checkDefaultConstraints(typ JSONType, c Constraints, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	switch t := sp.ResolveType(nonNullType(typ)).(type) {
+	case JSONString:
+		if t.Format == "byte" {
+			break
+		}
+		s := v.(string)
+		if c.MinLength > 0 && utf8.RuneCountInString(s) < c.MinLength {
+			return fmt.Errorf("%q must be at least %d characters long", s, c.MinLength)
+		}
+		if c.MaxLength > 0 && utf8.RuneCountInString(s) > c.MaxLength {
+			return fmt.Errorf("%q must be at most %d characters long", s, c.MaxLength)
+		}
+		if c.Pattern != "" && !regexp.MustCompile(c.Pattern).MatchString(s) {
+			return fmt.Errorf("%q must match the pattern %s", s, c.Pattern)
+		}
+		if c.Format != "" && !matchesFormat(c.Format, s) {
+			return fmt.Errorf("%q must be a %s", s, c.Format)
+		}
+	case JSONInteger, JSONNumber:
+		f := v.(float64)
+		lit := strconv.FormatFloat(f, 'g', -1, 64)
+		if c.Minimum != nil && (f < *c.Minimum || c.ExclusiveMinimum && f == *c.Minimum) {
+			return fmt.Errorf("%s is less than the minimum %v", lit, *c.Minimum)
+		}
+		if c.Maximum != nil && (f > *c.Maximum || c.ExclusiveMaximum && f == *c.Maximum) {
+			return fmt.Errorf("%s is greater than the maximum %v", lit, *c.Maximum)
+		}
+		if c.MultipleOf > 0 && math.Mod(f, c.MultipleOf) != 0 {
+			return fmt.Errorf("%s must be a multiple of %v", lit, c.MultipleOf)
+		}
+	case JSONArray:
+		a := v.([]interface{})
+		if c.MinItems > 0 && len(a) < c.MinItems {
+			return fmt.Errorf("must have at least %d items", c.MinItems)
+		}
+		if c.MaxItems > 0 && len(a) > c.MaxItems {
+			return fmt.Errorf("must have at most %d items", c.MaxItems)
+		}
+		for i, item := range a {
+			if c.UniqueItems {
+				for j := i + 1; j < len(a); j++ {
+					if reflect.DeepEqual(item, a[j]) {
+						return fmt.Errorf("items %d and %d are duplicates", i, j)
+					}
+				}
+			}
+			if err := sp.checkDefaultConstraints(t.Items, t.ItemConstraints, item); err != nil {
+				return fmt.Errorf("item %d: %v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// matchesFormat returns true if the string s has the format, one of the checkedFormats.
+func matchesFormat(format string, s string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", s)
+		return err == nil
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.IsAbs()
+	case "email":
+		a, err := mail.ParseAddress(s)
+		return err == nil && a.Address == s
+	default:
+		return regexp.MustCompile(formatPatterns[format]).MatchString(s)
+	}
+}
+
+// defaultLiteral returns the Go expression of the default value v, of type typ and Go type goType.
+// If typed is false, the expression may be an untyped constant, e.g in a composite literal.
+func (sp *SchemaParser) defaultLiteral(typ JSONType, goType string, v interface{}, typed bool) string {
+	switch t := sp.ResolveType(nonNullType(typ)).(type) {
+	case JSONString:
+		if goType != "string" {
+			if t.Format == "byte" {
+				b, _ := base64.StdEncoding.DecodeString(v.(string))
+				return fmt.Sprintf("%s(%q)", goType, b)
+			}
+			return fmt.Sprintf("%s(%q)", goType, v)
+		}
+		return strconv.Quote(v.(string))
+	case JSONEnum:
+		return t.ConstName(v.(string))
+	case JSONDateTime:
+		d, _ := time.Parse(time.RFC3339, v.(string))
+		d = d.UTC()
+		return fmt.Sprintf("time.Date(%d, %d, %d, %d, %d, %d, %d, time.UTC)", d.Year(), d.Month(), d.Day(), d.Hour(), d.Minute(), d.Second(), d.Nanosecond())
+	case JSONBoolean:
+		return strconv.FormatBool(v.(bool))
+	case JSONInteger:
+		lit := strconv.FormatInt(int64(v.(float64)), 10)
+		if typed && goType != "int" {
+			return fmt.Sprintf("%s(%s)", goType, lit)
+		}
+		return lit
+	case JSONNumber:
+		lit := strconv.FormatFloat(v.(float64), 'g', -1, 64)
+		if typed && goType != "float64" {
+			return fmt.Sprintf("%s(%s)", goType, lit)
+		}
+		if typed && !strings.ContainsAny(lit, ".e") {
+			// The constant would be an int.
+			lit += ".0"
+		}
+		return lit
+	case JSONArray:
+		itemGoType := sp.JSONToGoType(t.Items, false)
+		items := v.([]interface{})
+		lits := make([]string, len(items))
+		for i, item := range items {
+			lits[i] = sp.defaultLiteral(t.Items, itemGoType, item, false)
+		}
+		return fmt.Sprintf("%s{%s}", goType, strings.Join(lits, ", "))
+	}
+	return ""
+}
+
+// TypeNeedsDefaults returns true if an applyDefaults() method is generated for j.
+//
+// This is the case for named objects having fields with a default value,
+// or fields or embedded objects whose own type needs defaults.
+// Types already existing in the target package are assumed to have no applyDefaults() method.
+func (t *Template) TypeNeedsDefaults(j JSONType) bool {
+	return t.typeNeedsDefaults(j, make(map[string]bool))
+}
+
+func (t *Template) typeNeedsDefaults(j JSONType, visited map[string]bool) bool {
+	jo, ok := t.ctx.Schema.ResolveType(j).(JSONObject)
+	if !ok || jo.isEmpty() {
+		return false
+	}
+	if t.isExistingType(jo.TypeName()) {
+		return false
+	}
+	if visited[jo.TypeName()] {
+		return false
+	}
+	visited[jo.TypeName()] = true
+	for _, e := range jo.Embedded {
+		if t.typeNeedsDefaults(e, visited) {
+			return true
+		}
+	}
+	for _, f := range jo.Fields {
+		if t.fieldHasDefault(f) {
+			return true
+		}
+		if t.typeNeedsDefaults(t.fieldItemType(f), visited) {
+			return true
+		}
+	}
+	if jo.AdditionalProperties != nil {
+		return t.typeNeedsDefaults(jo.AdditionalProperties, visited)
+	}
+	return false
+}
+
+// fieldHasDefault returns true if the field f is set to its default value when absent.
+//
+// The absence of a required field which can't be nil can't be told apart from its zero value,
+// so its default value is ignored.
+func (t *Template) fieldHasDefault(f JSONField) bool {
+	if f.Default == nil {
+		return false
+	}
+	goType, isPtr := t.ctx.Schema.jsonFieldGoType(f)
+	return isPtr || isNilableGoType(goType)
+}
+
+// fieldItemType returns the type of the values held by the field f:
+// the type of its items or values for an array or a map, its own type otherwise.
+func (t *Template) fieldItemType(f JSONField) JSONType {
+	typ := nonNullType(f.Type)
+	switch c := t.ctx.Schema.ResolveType(typ).(type) {
+	case JSONArray:
+		return c.Items
+	case JSONMap:
+		return c.Values
+	}
+	return typ
+}
+
+// PrintApplyDefaultsFunc returns the Go source code of the applyDefaults() method of j,
+// or "" if j doesn't need defaults.
+func (t *Template) PrintApplyDefaultsFunc(j JSONType) string {
+	if !t.TypeNeedsDefaults(j) {
+		return ""
+	}
+	sp := t.ctx.Schema
+	jo := sp.ResolveType(j).(JSONObject)
+	typeName := sp.JSONToGoType(jo, false)
+	recv := t.Varname(typeName)
+	switch recv {
+	case "v", "i", "key", "item":
+		recv = "o"
+	}
+
+	var body bytes.Buffer
+	for _, e := range jo.Embedded {
+		if t.TypeNeedsDefaults(e) {
+			fmt.Fprintf(&body, "%s.%s.applyDefaults()\n", recv, sp.JSONToGoType(e, false))
+		}
+	}
+	for _, f := range jo.Fields {
+		goType, isPtr := sp.jsonFieldGoType(f)
+		fieldExpr := fmt.Sprintf("%s.%s", recv, sp.goFieldName(f))
+		if t.fieldHasDefault(f) {
+			if isPtr {
+				fmt.Fprintf(&body, "if %s == nil {\nv := %s\n%s = &v\n}\n", fieldExpr, sp.defaultLiteral(f.Type, goType, f.Default, true), fieldExpr)
+			} else {
+				fmt.Fprintf(&body, "if %s == nil {\n%s = %s\n}\n", fieldExpr, fieldExpr, sp.defaultLiteral(f.Type, goType, f.Default, true))
+			}
+		}
+		itemType := t.fieldItemType(f)
+		if !t.TypeNeedsDefaults(itemType) {
+			continue
+		}
+		switch sp.ResolveType(nonNullType(f.Type)).(type) {
+		case JSONArray:
+			fmt.Fprintf(&body, "for i := range %s {\n%s[i].applyDefaults()\n}\n", fieldExpr, fieldExpr)
+		case JSONMap:
+			fmt.Fprintf(&body, "for key, item := range %s {\nitem.applyDefaults()\n%s[key] = item\n}\n", fieldExpr, fieldExpr)
+		default:
+			if isPtr {
+				fmt.Fprintf(&body, "if %s != nil {\n%s.applyDefaults()\n}\n", fieldExpr, fieldExpr)
+			} else {
+				fmt.Fprintf(&body, "%s.applyDefaults()\n", fieldExpr)
+			}
+		}
+	}
+	if jo.AdditionalProperties != nil && t.TypeNeedsDefaults(jo.AdditionalProperties) {
+		fmt.Fprintf(&body, "for key, item := range %s.AdditionalProperties {\nitem.applyDefaults()\n%s.AdditionalProperties[key] = item\n}\n", recv, recv)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// applyDefaults sets the absent fields of the %s to their default value in its JSON Schema.\n", typeName)
+	fmt.Fprintf(&buf, "func (%s *%s) applyDefaults() {\n", recv, typeName)
+	_, _ = buf.Write(body.Bytes())
+	_, _ = buf.WriteString("}\n")
+	return buf.String()
+}
@@ -0,0 +1,213 @@
+package dispel
+
+import (
+	"bytes"
+	"testing"
+)
+
+const defaultedSpellsSchema = `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "required": ["name"],
+            "links": [
+                {
+                    "href": "/spells",
+                    "method": "POST",
+                    "rel": "create",
+                    "schema": {
+                        "$ref": "#/definitions/spell"
+                    }
+                }
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "power": {
+                    "type": "integer",
+                    "format": "int32",
+                    "default": 10
+                },
+                "ratio": {
+                    "type": "number",
+                    "default": 2
+                },
+                "element": {
+                    "type": "string",
+                    "enum": ["fire", "water"],
+                    "default": "water"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "default": ["common"]
+                },
+                "runes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rune"
+                    }
+                }
+            }
+        },
+        "rune": {
+            "properties": {
+                "glyph": {
+                    "type": "string",
+                    "default": "*"
+                }
+            }
+        }
+    },
+    "properties": {
+        "spell": {
+            "$ref": "#/definitions/spell"
+        }
+    }
+}`
+
+func TestTemplateTypesWithDefaults(t *testing.T) {
+	out := generateTemplate(t, defaultedSpellsSchema, typesTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	expectedFuncs := []string{`// applyDefaults sets the absent fields of the Rune to their default value in its JSON Schema.
+func (r *Rune) applyDefaults() {
+	if r.Glyph == nil {
+		v := "*"
+		r.Glyph = &v
+	}
+}
+`, `// applyDefaults sets the absent fields of the Spell to their default value in its JSON Schema.
+func (s *Spell) applyDefaults() {
+	if s.Power == nil {
+		v := int32(10)
+		s.Power = &v
+	}
+	if s.Ratio == nil {
+		v := 2.0
+		s.Ratio = &v
+	}
+	if s.Element == nil {
+		v := SpellElementWater
+		s.Element = &v
+	}
+	if s.Tags == nil {
+		s.Tags = []string{"common"}
+	}
+	for i := range s.Runes {
+		s.Runes[i].applyDefaults()
+	}
+}
+`}
+	for _, expectedFunc := range expectedFuncs {
+		if !bytes.Contains(out, []byte(expectedFunc)) {
+			t.Errorf("expected %q in %s", expectedFunc, out)
+		}
+	}
+}
+
+func TestTemplateHandlersWithDefaults(t *testing.T) {
+	out := generateTemplate(t, defaultedSpellsSchema, handlersTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	expectedCall := `			if err := hd.Decode(w, r, &vreq); err != nil {
+				return http.StatusBadRequest, err
+			}
+			vreq.applyDefaults()
+`
+	if !bytes.Contains(out, []byte(expectedCall)) {
+		t.Errorf("expected %q in %s", expectedCall, out)
+	}
+}
+
+func TestInvalidDefault(t *testing.T) {
+	tests := []struct {
+		Type    string
+		Default string
+	}{
+		{`"type": "string"`, `1`},
+		{`"type": "integer"`, `1.5`},
+		{`"type": "integer", "format": "int32"`, `4294967296`},
+		{`"type": "boolean"`, `"true"`},
+		{`"type": "string", "format": "date-time"`, `"yesterday"`},
+		{`"type": "string", "format": "byte"`, `"not base64!"`},
+		{`"type": "string", "enum": ["fire", "water"]`, `"earth"`},
+		{`"type": "array", "items": {"type": "integer"}`, `[1, "2"]`},
+		{`"type": "string", "minLength": 3`, `"x"`},
+		{`"type": "string", "pattern": "^[a-z]+$"`, `"X"`},
+		{`"type": "string", "format": "email"`, `"nobody"`},
+		{`"type": "integer", "minimum": 1, "exclusiveMinimum": true`, `1`},
+		{`"type": "number", "multipleOf": 0.5`, `0.3`},
+		{`"type": "array", "items": {"type": "string"}, "uniqueItems": true`, `["a", "a"]`},
+		{`"type": "array", "items": {"type": "string", "minLength": 3}`, `["x"]`},
+	}
+	for _, test := range tests {
+		schema := getSchemaString(t, `{
+    "type": "object",
+    "properties": {
+        "value": {`+test.Type+`, "default": `+test.Default+`}
+    }
+}`)
+		if t.Failed() {
+			return
+		}
+		sp := &SchemaParser{RootSchema: schema}
+		_, err := sp.JSONTypeFromSchema("Spell", schema, "")
+		if _, ok := err.(InvalidSchemaError); !ok {
+			t.Errorf("%s with default %s: expected an InvalidSchemaError, got %#v", test.Type, test.Default, err)
+		}
+	}
+}
+
+func TestRequiredFieldDefault(t *testing.T) {
+	schema := `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "required": ["power", "tags"],
+            "links": [
+                {"href": "/spells", "method": "POST", "rel": "create", "schema": {"$ref": "#/definitions/spell"}}
+            ],
+            "properties": {
+                "power": {"type": "integer", "default": 10},
+                "tags": {"type": "array", "items": {"type": "string"}, "default": ["common"]}
+            }
+        }
+    },
+    "properties": {
+        "spell": {"$ref": "#/definitions/spell"}
+    }
+}`
+	// An absent required integer can't be told apart from 0: its default value isn't applied,
+	// unlike the one of a required array, which is nil.
+	out := generateTemplate(t, schema, typesTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	expectedFunc := `func (s *Spell) applyDefaults() {
+	if s.Tags == nil {
+		s.Tags = []string{"common"}
+	}
+}
+`
+	if !bytes.Contains(out, []byte(expectedFunc)) {
+		t.Errorf("expected %q in %s", expectedFunc, out)
+	}
+
+	sp := &SchemaParser{RootSchema: getSchemaString(t, schema)}
+	if t.Failed() {
+		return
+	}
+	issues := sp.Lint()
+	expectedIssue := "#/definitions/spell/properties/power: property power is required, its default value is never applied"
+	if len(issues) != 1 || issues[0].String() != expectedIssue {
+		t.Errorf("expected the issue %q, got %v", expectedIssue, issues)
+	}
+}
@@ -6,15 +6,47 @@
 //
 // JSON Schema
 //
-// Usage of this package usually begins with parsing a JSON Schema and the routes it contains:
+// Usage of this package usually begins with parsing a JSON Schema, written in JSON or YAML, and the routes it contains:
 //
-//     var schema dispel.Schema
-//     err := json.NewDecoder(reader).Decode(&schema)
+//     schema, err := dispel.ParseSchema(reader, dispel.FormatJSON)
 //     // ...
-//     schemaParser := &dispel.SchemaParser{RootSchema: &schema}
+//     schemaParser := &dispel.SchemaParser{RootSchema: schema}
 //     routes, err := schemaParser.ParseRoutes()
 //     // ...
 //
+// The routes are the links of the root schema, of its properties and of its definitions, nested ones included.
+// Documents from draft-04 to 2020-12 are supported, selected by their $schema, and the documents they
+// reference are loaded with the RefLoader of the SchemaParser.
+//
+// The errors about the schema, InvalidSchemaError and InvalidSchemaRefError, carry the JSON pointer of the offending node,
+// and its file, line and column if it was read with ParseSchema or ParseSchemaFile.
+// With CollectErrors, ParseRoutes reports all of them at once, as SchemaErrors.
+// Lint reports the features of the schema which dispel ignores or which are likely mistakes.
+//
+// Generated Types
+//
+// The types of the request and response bodies are generated from their schemas:
+//
+//  * optional properties are pointers tagged with omitempty, like the types listed with "null"
+//  * the fields are in the order of the properties, or sorted by name with SortFields
+//  * string enums are string types with one constant per value; an inline enum is named after its object, e.g CatKind
+//  * oneOf, anyOf and lists of types are structs with a field per variant, picked by the x-discriminator property if any
+//  * the allOf members which are a $ref to an object are embedded, unless they share properties; the others are merged
+//  * additionalProperties and patternProperties are maps, or the AdditionalProperties field of an object with properties
+//  * the formats int32, int64, float, byte and date-time are int32, int64, float32, []byte and time.Time,
+//    and GoTypes maps formats and $refs to any Go type
+//  * the readOnly properties are omitted from the input variants decoded from request bodies, e.g ServerInput
+//  * the validation keywords, and the formats date, time, duration, uri, email and uuid, are checked by a Validate() method;
+//    the request bodies failing it are rejected with 422 Unprocessable Entity
+//  * the default values of the properties are set by an applyDefaults() method when they're absent from requests
+//  * the descriptions of the schemas are the doc comments of the types and fields
+//
+// The route params are passed to the handler funcs with their Go type, and the query strings, described by
+// the {?var} expansions of hrefs and the schema of GET and DELETE links, are decoded in a struct.
+//
+// The names of the types, fields, routes, handler funcs and route params are built by the Namer of the SchemaParser,
+// DefaultNamer if nil, which writes initialisms in all caps. The x-go-name keyword overrides them.
+//
 // Then you want to generate code using these routes. Dispel provides several builtin templates
 // which provide code generation for registering API routes and API handlers, generating input and output types
 // for the data structures of these routes, and default implementations of API handlers.
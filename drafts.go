@@ -0,0 +1,167 @@
+package dispel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Draft identifies a version of the JSON Schema specification.
+//
+// The draft of a schema document is selected by the URI of its $schema keyword.
+// It tells apart the keywords whose meaning changed between drafts:
+//   - the identifier of a schema is its id in draft-04, its $id in the later drafts
+//   - exclusiveMaximum and exclusiveMinimum are booleans in draft-04, numbers in the later drafts
+//   - const is a keyword since draft-06
+//
+// The keywords which were only added, like $defs, examples, or the hrefSchema and targetHints of links,
+// are understood whatever the draft.
+type Draft int
+
+// The drafts known by dispel.
+const (
+	Draft04 Draft = iota
+	Draft06
+	Draft07
+	Draft201909
+	Draft202012
+)
+
+// draftURIs maps the path of the $schema URIs of json-schema.org to their Draft.
+var draftURIs = map[string]Draft{
+	"draft-04":      Draft04,
+	"draft-06":      Draft06,
+	"draft-07":      Draft07,
+	"draft/2019-09": Draft201909,
+	"draft/2020-12": Draft202012,
+}
+
+// String returns the name of the draft, e.g draft-07 or 2020-12.
+func (d Draft) String() string {
+	switch d {
+	case Draft04:
+		return "draft-04"
+	case Draft06:
+		return "draft-06"
+	case Draft07:
+		return "draft-07"
+	case Draft201909:
+		return "2019-09"
+	case Draft202012:
+		return "2020-12"
+	}
+	return fmt.Sprintf("Draft(%d)", int(d))
+}
+
+// ParseDraft returns the Draft of the $schema URI uri, e.g http://json-schema.org/draft-07/hyper-schema#.
+// Draft04 and an error are returned if the URI isn't the one of a draft known by dispel.
+func ParseDraft(uri string) (Draft, error) {
+	p := uri
+	for _, prefix := range []string{"http://", "https://"} {
+		p = strings.TrimPrefix(p, prefix)
+	}
+	p = strings.TrimPrefix(p, "json-schema.org/")
+	for prefix, d := range draftURIs {
+		if strings.HasPrefix(p, prefix+"/") {
+			return d, nil
+		}
+	}
+	return Draft04, fmt.Errorf("unsupported $schema %q", uri)
+}
+
+// DocumentID returns the identifier of s, from its id or $id keyword according to the draft
+// selected by its $schema. This is the keyword which identifies the root schema of a document.
+func (s *Schema) DocumentID() string {
+	d, _ := ParseDraft(s.Schema)
+	return s.id(d)
+}
+
+// id returns the identifier of s in a document of the draft d.
+func (s *Schema) id(d Draft) string {
+	if d == Draft04 {
+		return s.ID
+	}
+	return s.DollarID
+}
+
+// draft returns the draft of the root schema, or of the document of s if it declares its own.
+func (sp *SchemaParser) draft(s *Schema) Draft {
+	sp.rootURI()
+	if d, ok := sp.drafts[s]; ok {
+		return d
+	}
+	return sp.drafts[sp.RootSchema]
+}
+
+// documentDraft returns the draft of the schema document doc, which is the one of the root schema
+// if doc has no $schema. Documents whose $schema isn't a known draft, e.g a hyper-schema derived
+// from draft-04, are draft-04 documents.
+func (sp *SchemaParser) documentDraft(doc *Schema) Draft {
+	if doc.Schema == "" {
+		if doc == sp.RootSchema {
+			return Draft04
+		}
+		return sp.draft(sp.RootSchema)
+	}
+	d, err := ParseDraft(doc.Schema)
+	if err != nil {
+		sp.logf("[warn] %v, assuming draft-04", err)
+	}
+	return d
+}
+
+// exclusiveKeyword returns the relative JSON pointer of the exclusiveMaximum keyword if isMaximum,
+// or else of the exclusiveMinimum keyword.
+func exclusiveKeyword(isMaximum bool) string {
+	if isMaximum {
+		return "/exclusiveMaximum"
+	}
+	return "/exclusiveMinimum"
+}
+
+// boundsFromSchema returns the maximum and minimum of the schema of the draft d,
+// and whether they are exclusive.
+func (sp *SchemaParser) boundsFromSchema(schema *Schema, d Draft) (maximum *float64, exclusiveMaximum bool, minimum *float64, exclusiveMinimum bool, err error) {
+	if d == Draft04 {
+		if schema.ExclusiveMaximumValue != nil || schema.ExclusiveMinimumValue != nil {
+			return nil, false, nil, false, sp.schemaError(schema, exclusiveKeyword(schema.ExclusiveMaximumValue != nil), "exclusiveMaximum and exclusiveMinimum must be booleans in draft-04")
+		}
+		return schema.Maximum, schema.ExclusiveMaximum, schema.Minimum, schema.ExclusiveMinimum, nil
+	}
+	if schema.ExclusiveMaximum || schema.ExclusiveMinimum {
+		return nil, false, nil, false, sp.schemaError(schema, exclusiveKeyword(schema.ExclusiveMaximum), fmt.Sprintf("exclusiveMaximum and exclusiveMinimum must be numbers in %s", d))
+	}
+	maximum, minimum = schema.Maximum, schema.Minimum
+	// The tightest of the inclusive and exclusive bounds applies.
+	if v := schema.ExclusiveMaximumValue; v != nil && (maximum == nil || *v <= *maximum) {
+		maximum, exclusiveMaximum = v, true
+	}
+	if v := schema.ExclusiveMinimumValue; v != nil && (minimum == nil || *v >= *minimum) {
+		minimum, exclusiveMinimum = v, true
+	}
+	return maximum, exclusiveMaximum, minimum, exclusiveMinimum, nil
+}
+
+// constValue returns the value of the const keyword of the schema of the draft d,
+// or nil if it has none.
+func constValue(schema *Schema, d Draft) interface{} {
+	if d == Draft04 {
+		return nil
+	}
+	return schema.Const
+}
+
+// constType returns the JSON type of the value v of a const keyword, or "" if it has none.
+func constType(v interface{}) string {
+	switch n := v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if n == float64(int64(n)) {
+			return "integer"
+		}
+		return "number"
+	}
+	return ""
+}
@@ -0,0 +1,239 @@
+package dispel
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseDraft(t *testing.T) {
+	tests := []struct {
+		URI      string
+		Expected Draft
+		Known    bool
+	}{
+		{"http://json-schema.org/draft-04/hyper-schema", Draft04, true},
+		{"http://json-schema.org/draft-04/schema#", Draft04, true},
+		{"http://json-schema.org/draft-06/schema#", Draft06, true},
+		{"http://json-schema.org/draft-07/hyper-schema#", Draft07, true},
+		{"https://json-schema.org/draft/2019-09/schema", Draft201909, true},
+		{"https://json-schema.org/draft/2020-12/hyper-schema", Draft202012, true},
+		{"http://interagent.github.io/interagent-hyper-schema", Draft04, false},
+	}
+	for _, test := range tests {
+		d, err := ParseDraft(test.URI)
+		if d != test.Expected {
+			t.Errorf("%s: expected %s, got %s", test.URI, test.Expected, d)
+		}
+		if known := err == nil; known != test.Known {
+			t.Errorf("%s: expected known %v, got error %v", test.URI, test.Known, err)
+		}
+	}
+}
+
+func TestUnmarshalExclusiveBounds(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "properties": {
+        "boolean": {"type": "integer", "maximum": 10, "exclusiveMaximum": true},
+        "numeric": {"type": "integer", "exclusiveMinimum": 0}
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	if p := schema.Properties["boolean"]; !p.ExclusiveMaximum || p.ExclusiveMaximumValue != nil {
+		t.Errorf("expected a boolean exclusiveMaximum, got %#v", p)
+	}
+	if p := schema.Properties["numeric"]; p.ExclusiveMinimum || p.ExclusiveMinimumValue == nil || *p.ExclusiveMinimumValue != 0 {
+		t.Errorf("expected a numeric exclusiveMinimum, got %#v", p)
+	}
+
+	b, err := json.Marshal(schema.Properties["numeric"])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if expected := `{"type":"integer","exclusiveMinimum":0}`; string(b) != expected {
+		t.Errorf("expected %s, got %s", expected, b)
+	}
+}
+
+func TestExclusiveBoundsOfDraft(t *testing.T) {
+	tests := []struct {
+		Draft    string
+		Bounds   string
+		Valid    bool
+		Expected Constraints
+	}{
+		{"http://json-schema.org/draft-04/schema#", `"minimum": 0, "exclusiveMinimum": true`, true, Constraints{Minimum: fptr(0), ExclusiveMinimum: true}},
+		{"http://json-schema.org/draft-04/schema#", `"exclusiveMinimum": 0`, false, Constraints{}},
+		{"http://json-schema.org/draft-07/schema#", `"exclusiveMinimum": 0`, true, Constraints{Minimum: fptr(0), ExclusiveMinimum: true}},
+		{"http://json-schema.org/draft-07/schema#", `"minimum": 1, "exclusiveMinimum": 0`, true, Constraints{Minimum: fptr(1)}},
+		{"http://json-schema.org/draft-07/schema#", `"maximum": 10, "exclusiveMaximum": 5`, true, Constraints{Maximum: fptr(5), ExclusiveMaximum: true}},
+		{"http://json-schema.org/draft-07/schema#", `"minimum": 0, "exclusiveMinimum": true`, false, Constraints{}},
+	}
+	for _, test := range tests {
+		schema := getSchemaString(t, `{
+    "$schema": "`+test.Draft+`",
+    "type": "object",
+    "properties": {
+        "power": {"type": "integer", `+test.Bounds+`}
+    }
+}`)
+		if t.Failed() {
+			return
+		}
+		sp := &SchemaParser{RootSchema: schema}
+		typ, err := sp.JSONTypeFromSchema("Spell", schema, "")
+		if !test.Valid {
+			if _, ok := err.(InvalidSchemaError); !ok {
+				t.Errorf("%s %s: expected an InvalidSchemaError, got %#v", test.Draft, test.Bounds, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %s: %v", test.Draft, test.Bounds, err)
+			continue
+		}
+		if c := typ.(JSONObject).Fields[0].Constraints; !reflect.DeepEqual(test.Expected, c) {
+			t.Errorf("%s %s: expected %#v, got %#v", test.Draft, test.Bounds, test.Expected, c)
+		}
+	}
+}
+
+func fptr(f float64) *float64 {
+	return &f
+}
+
+func TestResolveIDOfDraft(t *testing.T) {
+	tests := []struct {
+		Draft string
+		Ref   string
+	}{
+		{"http://json-schema.org/draft-04/schema#", `"id": "#element"`},
+		{"http://json-schema.org/draft-07/schema#", `"$id": "#element"`},
+	}
+	for _, test := range tests {
+		schema := getSchemaString(t, `{
+    "$schema": "`+test.Draft+`",
+    "definitions": {
+        "element": {`+test.Ref+`, "type": "string"},
+        "other": {"id": "#other", "$id": "#other", "type": "string"}
+    },
+    "properties": {
+        "element": {"$ref": "#element"}
+    }
+}`)
+		if t.Failed() {
+			return
+		}
+		sp := &SchemaParser{RootSchema: schema}
+		s, err := sp.ResolveSchemaRef("#element", schema)
+		if err != nil {
+			t.Errorf("%s: %v", test.Draft, err)
+			continue
+		}
+		if s != schema.Definitions["element"] {
+			t.Errorf("%s: expected the element definition, got %#v", test.Draft, s)
+		}
+	}
+
+	// The id keyword of the other drafts identifies nothing.
+	schema := getSchemaString(t, `{
+    "$schema": "http://json-schema.org/draft-07/schema#",
+    "definitions": {
+        "element": {"id": "#element", "type": "string"}
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	if _, err := sp.ResolveSchemaRef("#element", schema); err == nil {
+		t.Errorf("expected an error resolving the draft-04 id of a draft-07 schema")
+	}
+}
+
+func TestParseRoutesOfDraft202012(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "$schema": "https://json-schema.org/draft/2020-12/hyper-schema",
+    "type": "object",
+    "$defs": {
+        "spell": {
+            "$defs": {
+                "name": {"type": "string", "examples": ["fireball"]},
+                "kind": {"const": "spell"}
+            },
+            "properties": {
+                "name": {"$ref": "#/$defs/spell/$defs/name"},
+                "kind": {"$ref": "#/$defs/spell/$defs/kind"},
+                "version": {"const": 2}
+            },
+            "links": [
+                {
+                    "href": "/spells{?page}",
+                    "rel": "instances",
+                    "targetHints": {"allow": ["GET"]},
+                    "hrefSchema": {
+                        "properties": {
+                            "page": {"type": "integer"}
+                        }
+                    }
+                },
+                {
+                    "href": "/spells/{(#/$defs/spell/$defs/name)}",
+                    "rel": "update",
+                    "targetHints": {"allow": "PUT"},
+                    "submissionSchema": {"$ref": "#/$defs/spell"}
+                }
+            ]
+        }
+    },
+    "properties": {
+        "spell": {
+            "$ref": "#/$defs/spell"
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(routes) != 2 {
+		t.Errorf("expected 2 routes, got %#v", routes)
+		return
+	}
+
+	list, update := routes[0], routes[1]
+	if list.Method != "GET" || list.Path != "/spells" {
+		t.Errorf("expected GET /spells, got %s %s", list.Method, list.Path)
+	}
+	expectedQuery := JSONObject{
+		Name:   "InstancesSpellQuery",
+		Fields: JSONFieldList{{Name: "page", Type: JSONInteger{}}},
+	}
+	if !reflect.DeepEqual(expectedQuery, list.QueryType) {
+		t.Errorf("expected %#v, got %#v", expectedQuery, list.QueryType)
+	}
+
+	if update.Method != "PUT" || update.Path != "/spells/{spell-name}" {
+		t.Errorf("expected PUT /spells/{spell-name}, got %s %s", update.Method, update.Path)
+	}
+	expectedIn := JSONObject{
+		Name: "Spell",
+		ref:  "#/$defs/spell",
+		Fields: JSONFieldList{
+			{Name: "name", Type: JSONString{ref: "#/$defs/spell/$defs/name"}},
+			{Name: "kind", Type: JSONEnum{Name: "SpellKind", ref: "#/$defs/spell/$defs/kind", Values: []string{"spell"}}},
+			{Name: "version", Type: JSONInteger{}},
+		},
+	}
+	if !reflect.DeepEqual(expectedIn, update.InType) {
+		t.Errorf("expected %#v, got %#v", expectedIn, update.InType)
+	}
+}
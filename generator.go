@@ -110,7 +110,9 @@ func NewTemplate(sp *SchemaParser, text string) (*Template, error) {
 	t, err := template.New("").Funcs(template.FuncMap{
 		"tolower":    strings.ToLower,
 		"capitalize": capitalize,
-		"symbolName": symbolName,
+		"symbolName": func(s string) string {
+			return sp.namer().SymbolName(s)
+		},
 		"hasItem": func(a []string, s string) bool {
 			for _, item := range a {
 				if s == item {
@@ -126,10 +128,29 @@ func NewTemplate(sp *SchemaParser, text string) (*Template, error) {
 		"printTypeName": func(j JSONType) string {
 			return sp.JSONToGoType(j, false)
 		},
-		"typeNeedsAddr":       tmpl.TypeNeedsAddr,
-		"printSmartDerefType": tmpl.PrintSmartDerefType,
-		"routesForType":       tmpl.RoutesForType,
-		"varname":             tmpl.Varname,
+		"typeNeedsAddr":             tmpl.TypeNeedsAddr,
+		"printSmartDerefType":       tmpl.PrintSmartDerefType,
+		"routesForType":             tmpl.RoutesForType,
+		"varname":                   tmpl.Varname,
+		"typeNeedsValidation":       tmpl.TypeNeedsValidation,
+		"printValidateFunc":         tmpl.PrintValidateFunc,
+		"printValidationErrorsDecl": tmpl.PrintValidationErrorsDecl,
+		"typeNeedsDefaults":         tmpl.TypeNeedsDefaults,
+		"printApplyDefaultsFunc":    tmpl.PrintApplyDefaultsFunc,
+		"isEnum":                    tmpl.IsEnum,
+		"printUnionHelpersDecl":     tmpl.PrintUnionHelpersDecl,
+		"routeParamGoType":          tmpl.RouteParamGoType,
+		"printRouteParamParse":      tmpl.PrintRouteParamParse,
+		"printRouteParamFormat":     tmpl.PrintRouteParamFormat,
+		"handlersImports":           tmpl.HandlersImports,
+		"routesImports":             tmpl.RoutesImports,
+		"handlerFuncsImports":       tmpl.HandlerFuncsImports,
+		"queryTypes":                tmpl.QueryTypes,
+		"printQueryDecodeFunc":      tmpl.PrintQueryDecodeFunc,
+		"printQueryValuesFunc":      tmpl.PrintQueryValuesFunc,
+		"printTypeDescription":      tmpl.PrintTypeDescription,
+		"printLinkDoc":              tmpl.PrintLinkDoc,
+		"printResourceLinksDoc":     tmpl.PrintResourceLinksDoc,
 	}).Parse(text)
 	if err != nil {
 		return nil, err
@@ -163,27 +184,63 @@ LRoutesLoop:
 	return true
 }
 
+// Namer returns the Namer naming the Go identifiers of the generated code, the one of the SchemaParser.
+func (t *Template) Namer() Namer {
+	return t.Schema.namer()
+}
+
 // HandlerFuncName returns the name of a handlerfunc for a route method and name.
+// It's the x-go-name of the link of the route, if set.
 func (t *Template) HandlerFuncName(routeMethod string, routeName string) string {
-	return strings.ToLower(routeMethod) + symbolName(routeName)
+	if t.ctx != nil {
+		for _, route := range t.ctx.Routes {
+			if route.Name == routeName && route.Method == strings.ToUpper(routeMethod) && route.Link.GoName != "" {
+				return route.Link.GoName
+			}
+		}
+	}
+	return t.Namer().HandlerFuncName(routeMethod, routeName)
 }
 
 // TypeImports returns the list of packages to import for the types generated by dispel.
 func (t *Template) TypeImports() []string {
-	var imports []string
+	importsSet := make(map[string]bool)
 	for _, route := range t.ctx.Routes {
-		for _, typ := range []JSONType{route.InType, route.OutType} {
+		for _, typ := range []JSONType{route.InType, route.OutType, route.QueryType} {
 			if typ == nil {
 				continue
 			}
 			t.ctx.Routes.walkType(typ, func(jt JSONType) {
-				_, ok := jt.(JSONDateTime)
-				if ok {
-					imports = append(imports, "time")
+				switch j := jt.(type) {
+				case JSONDateTime:
+					importsSet["time"] = true
+				case JSONGoType:
+					if importPath := j.ImportPath(); importPath != "" {
+						importsSet[importPath] = true
+					}
 				}
 			})
 		}
 	}
+	for _, jtn := range t.ctx.Routes.JSONNamedTypes() {
+		if _, ok := jtn.(JSONEnum); ok && !t.isExistingType(jtn.TypeName()) {
+			importsSet["encoding/json"] = true
+			importsSet["fmt"] = true
+		}
+	}
+	if t.hasAdditionalProperties() {
+		importsSet["encoding/json"] = true
+	}
+	for _, imp := range t.unionImports() {
+		importsSet[imp] = true
+	}
+	for _, imp := range t.validationImports() {
+		importsSet[imp] = true
+	}
+	var imports []string
+	for imp := range importsSet {
+		imports = append(imports, imp)
+	}
 	sort.Strings(imports)
 	return imports
 }
@@ -197,13 +254,110 @@ func (t *Template) PrintTypeDef(j JSONType) string {
 		return ""
 	case JSONObject:
 		// we don't want types aliased to an interface{}
-		if len(jt.Fields) == 0 {
+		if jt.isEmpty() {
 			return ""
 		}
+		if jt.AdditionalProperties != nil {
+			return fmt.Sprintf("type %s %s\n\n%s", t.ctx.Schema.JSONToGoType(j, false), t.ctx.Schema.JSONToGoType(j, true), t.printAdditionalPropertiesMethods(jt))
+		}
+	case JSONEnum:
+		return t.printEnumTypeDef(jt)
+	case JSONUnion:
+		return t.printUnionTypeDef(jt)
 	}
 	return fmt.Sprintf("type %s %s", t.ctx.Schema.JSONToGoType(j, false), t.ctx.Schema.JSONToGoType(j, true))
 }
 
+// PrintTypeDescription returns the description of the schema of j as a paragraph of its doc comment,
+// or "" if it has none.
+func (t *Template) PrintTypeDescription(j JSONType) string {
+	var description string
+	switch jt := t.ctx.Schema.ResolveType(j).(type) {
+	case JSONObject:
+		description = jt.Description
+	case JSONEnum:
+		description = jt.Description
+	case JSONUnion:
+		description = jt.Description
+	}
+	if c := docComment(description); c != "" {
+		return "//\n" + c
+	}
+	return ""
+}
+
+// PrintLinkDoc returns the title and the description of the link l as sentences
+// of a doc comment, or "" if it has none.
+func (t *Template) PrintLinkDoc(l Link) string {
+	title := strings.TrimSpace(l.Title)
+	// A title without punctuation, followed by the description, would be a heading.
+	if title != "" && !strings.ContainsAny(title[len(title)-1:], ".!?") {
+		title += "."
+	}
+	var buf bytes.Buffer
+	for _, text := range []string{title, l.Description} {
+		if c := docComment(text); c != "" {
+			_, _ = buf.WriteString("//\n")
+			_, _ = buf.WriteString(c)
+		}
+	}
+	return buf.String()
+}
+
+// PrintResourceLinksDoc returns the lines of a doc comment listing the methods of the resource
+// with the title of their link, or "" if none has a title.
+func (t *Template) PrintResourceLinksDoc(rr ResourceRoute) string {
+	var buf bytes.Buffer
+	for _, method := range rr.Methods() {
+		if title := rr.MethodRouteIOMap[method].Title; title != "" {
+			_, _ = buf.WriteString(docComment(fmt.Sprintf("%s: %s", method, title)))
+		}
+	}
+	return buf.String()
+}
+
+// printEnumTypeDef returns the Go type definition of an enum, along with a constant for each
+// of its values and the methods checking them.
+func (t *Template) printEnumTypeDef(e JSONEnum) string {
+	typeName := t.ctx.Schema.JSONToGoType(e, false)
+	recv := t.Varname(typeName)
+	if recv == "s" {
+		recv = "v"
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "type %s string\n\n", typeName)
+	fmt.Fprintf(&buf, "// Values of %s.\nconst (\n", typeName)
+	constNames := make([]string, 0, len(e.Values))
+	for _, v := range e.Values {
+		constName := e.ConstName(v)
+		constNames = append(constNames, constName)
+		fmt.Fprintf(&buf, "%s %s = %q\n", constName, typeName, v)
+	}
+	_, _ = buf.WriteString(")\n\n")
+	fmt.Fprintf(&buf, "// IsValid returns true if %s is one of the values of %s.\n", recv, typeName)
+	fmt.Fprintf(&buf, "func (%s %s) IsValid() bool {\nswitch %s {\ncase %s:\nreturn true\n}\nreturn false\n}\n\n", recv, typeName, recv, strings.Join(constNames, ", "))
+	fmt.Fprintf(&buf, "// UnmarshalJSON implements the json.Unmarshaler interface.\n// It fails if the JSON string is not one of the values of %s.\n", typeName)
+	fmt.Fprintf(&buf, "func (%s *%s) UnmarshalJSON(data []byte) error {\nvar s string\nif err := json.Unmarshal(data, &s); err != nil {\nreturn err\n}\n", recv, typeName)
+	fmt.Fprintf(&buf, "if !%s(s).IsValid() {\nreturn fmt.Errorf(\"invalid %s value %%q\", s)\n}\n*%s = %s(s)\nreturn nil\n}", typeName, typeName, recv, typeName)
+	return buf.String()
+}
+
+// IsEnum returns true if j is an enum.
+func (t *Template) IsEnum(j JSONType) bool {
+	_, ok := t.ctx.Schema.ResolveType(j).(JSONEnum)
+	return ok
+}
+
+// isExistingType returns true if the type named typeName is already defined by the user.
+func (t *Template) isExistingType(typeName string) bool {
+	for _, existingType := range t.ctx.ExistingTypes {
+		if existingType == typeName {
+			return true
+		}
+	}
+	return false
+}
+
 // TypeNeedsAddr returns true if it's necessary to take the address of a type.
 func (t *Template) TypeNeedsAddr(j JSONType) bool {
 	_, ok := j.(JSONArray)
@@ -213,17 +367,22 @@ func (t *Template) TypeNeedsAddr(j JSONType) bool {
 	return true
 }
 
-// PrintSmartDerefType returns a pointer to j if j is a JSONObject.
+// PrintSmartDerefType returns a pointer to j if j is a JSONObject or a JSONUnion.
 func (t *Template) PrintSmartDerefType(j JSONType) string {
 	// really smart
-	jo, ok := j.(JSONObject)
-	if ok && len(jo.Fields) > 0 {
+	switch jt := j.(type) {
+	case JSONObject:
+		if !jt.isEmpty() {
+			return "*" + t.ctx.Schema.JSONToGoType(j, false)
+		}
+	case JSONUnion:
 		return "*" + t.ctx.Schema.JSONToGoType(j, false)
 	}
 	return t.ctx.Schema.JSONToGoType(j, false)
 }
 
-// RoutesForType returns the routes in which j is involved, either as itself or as a slice of itself.
+// RoutesForType returns the routes in which j is involved, either as itself or as a slice of itself,
+// or as their query string.
 func (t *Template) RoutesForType(j JSONType) []RouteAndIOTypeNames {
 	var froutes []RouteAndIOTypeNames
 	def := t.ctx.Schema.JSONToGoType(j, false)
@@ -246,6 +405,14 @@ func (t *Template) RoutesForType(j JSONType) []RouteAndIOTypeNames {
 				})
 			}
 		}
+		if route.QueryType != nil {
+			if queryDef := t.ctx.Schema.JSONToGoType(route.QueryType, false); queryDef == def {
+				froutes = append(froutes, RouteAndIOTypeNames{
+					Route:         route,
+					QueryTypeName: queryDef,
+				})
+			}
+		}
 	}
 	sort.Sort(RoutesAndIOTypeNames(froutes))
 	return froutes
@@ -290,6 +457,7 @@ type RouteAndIOTypeNames struct {
 	Route          Route
 	InputTypeName  string
 	OutputTypeName string
+	QueryTypeName  string
 }
 
 // RoutesAndIOTypeNames is defined for sorting RouteIOAndTypeNames by method and path.
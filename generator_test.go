@@ -71,15 +71,23 @@ func registerRoutes(rr RouteRegisterer) {
 
 // Constants defining the name of all the routes of the API.
 const (
+    // GET: List spells
+    // POST: Create a spell
     routeSpells = "spells"
+    // GET: Info for a spell
     routeSpellsOne = "spells.one"
 )
 
 // Types defining the parameters of all the routes of the API.
 type (
     // RouteSpells represents the parameters of the path /spells.
+    //
+    // GET: List spells
+    // POST: Create a spell
     RouteSpells struct{}
     // RouteSpellsOne represents the parameters of the path /spells/{spell-name}.
+    //
+    // GET: Info for a spell
     RouteSpellsOne struct{
         SpellName string
     }
@@ -283,16 +291,22 @@ import (
 )
 
 // getSpells is the handler for GET /spells.
+//
+// List spells.
 func (a *App) getSpells(w http.ResponseWriter, r *http.Request) (int, []Spell, error) {
     return http.StatusNotImplemented, nil, nil
 }
 
 // postSpells is the handler for POST /spells.
+//
+// Create a spell.
 func (a *App) postSpells(w http.ResponseWriter, r *http.Request, vreq *Spell) (int, *Spell, error) {
     return http.StatusNotImplemented, nil, nil
 }
 
 // getSpellsOne is the handler for GET /spells/{spell-name}.
+//
+// Info for a spell.
 func (a *App) getSpellsOne(w http.ResponseWriter, r *http.Request, spellName string) (int, *Spell, error) {
     return http.StatusNotImplemented, nil, nil
 }
@@ -330,7 +344,7 @@ func TestTemplateTypesOneResource(t *testing.T) {
 	if t.Failed() {
 		return
 	}
-	sp := &SchemaParser{RootSchema: schema}
+	sp := &SchemaParser{RootSchema: schema, SortFields: true}
 	routes, err := sp.ParseRoutes()
 	if err != nil {
 		t.Error(err)
@@ -355,10 +369,10 @@ package %s
 //  * Response body of POST /spells
 //  * Response body of GET /spells/{spell-name}
 type Spell struct {
-    All bool     `+"`"+`json:"all"`+"`"+`
-    Element string `+"`"+`json:"element"`+"`"+`
-    Name string    `+"`"+`json:"name"`+"`"+`
-    Power int   `+"`"+`json:"power"`+"`"+`
+    All *bool     `+"`"+`json:"all,omitempty"`+"`"+`
+    Element *string `+"`"+`json:"element,omitempty"`+"`"+`
+    Name *string    `+"`"+`json:"name,omitempty"`+"`"+`
+    Power *int   `+"`"+`json:"power,omitempty"`+"`"+`
 }
 `, ctx.Prgm, ctx.PkgName)))
 	if err != nil {
@@ -454,7 +468,7 @@ func TestTemplateTypesWithImports(t *testing.T) {
 	if t.Failed() {
 		return
 	}
-	sp := &SchemaParser{RootSchema: schema}
+	sp := &SchemaParser{RootSchema: schema, SortFields: true}
 	routes, err := sp.ParseRoutes()
 	if err != nil {
 		t.Error(err)
@@ -478,12 +492,120 @@ import "time"
 //
 //  * Response body of GET /spells/{spell-name}
 type Spell struct {
-    All bool     `+"`"+`json:"all"`+"`"+`
-    Attrs interface{}   `+"`"+`json:"attrs"`+"`"+`
-    Element string `+"`"+`json:"element"`+"`"+`
-    LevelOn time.Time    `+"`"+`json:"level_on"`+"`"+`
+    All *bool     `+"`"+`json:"all,omitempty"`+"`"+`
+    Attrs interface{}   `+"`"+`json:"attrs,omitempty"`+"`"+`
+    Element *string `+"`"+`json:"element,omitempty"`+"`"+`
+    LevelOn *time.Time    `+"`"+`json:"level_on,omitempty"`+"`"+`
+    Name *string    `+"`"+`json:"name,omitempty"`+"`"+`
+    Power *int   `+"`"+`json:"power,omitempty"`+"`"+`
+}
+`, ctx.Prgm, ctx.PkgName)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tmpl, err := NewTemplate(sp, typesTmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Generate(&buf, ctx); err != nil {
+		t.Error(err)
+		return
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Log(buf.String())
+		t.Error(err)
+		return
+	}
+	if string(expectedOut) != string(out) {
+		t.Errorf("expected %#v, got %#v", string(expectedOut), string(out))
+		return
+	}
+}
+
+func TestTemplateTypesRequiredFields(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "required": ["name", "power", "targets"],
+            "links": [
+                {
+                    "href": "/spells",
+                    "method": "PATCH",
+                    "rel": "update",
+                    "schema": {
+                        "$ref": "#/definitions/spell"
+                    }
+                }
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "power": {
+                    "type": "integer"
+                },
+                "element": {
+                    "type": "string"
+                },
+                "targets": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        }
+    },
+    "properties": {
+        "spell": {
+            "$ref": "#/definitions/spell"
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema, SortFields: true}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx := &Context{
+		Prgm:                "dispel",
+		PkgName:             "handler",
+		Routes:              routes,
+		HandlerReceiverType: "*App",
+	}
+
+	expectedOut, err := format.Source([]byte(fmt.Sprintf(`// generated by %s; DO NOT EDIT
+
+package %s
+
+// Spell represents the data structure sent/received on the following routes:
+//
+//  * Request body of PATCH /spells
+type Spell struct {
+    Element *string `+"`"+`json:"element,omitempty"`+"`"+`
     Name string    `+"`"+`json:"name"`+"`"+`
     Power int   `+"`"+`json:"power"`+"`"+`
+    Tags []string   `+"`"+`json:"tags,omitempty"`+"`"+`
+    Targets []string   `+"`"+`json:"targets"`+"`"+`
 }
 `, ctx.Prgm, ctx.PkgName)))
 	if err != nil {
@@ -514,12 +636,367 @@ type Spell struct {
 	}
 }
 
+const enumSpellsSchema = `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "required": ["school"],
+            "definitions": {
+                "school": {
+                    "type": "string",
+                    "enum": ["fire", "dark-arts"]
+                }
+            },
+            "links": [
+                {
+                    "href": "/schools/{(#/definitions/spell/definitions/school)}/spells",
+                    "method": "POST",
+                    "rel": "create",
+                    "schema": {
+                        "$ref": "#/definitions/spell"
+                    }
+                }
+            ],
+            "properties": {
+                "school": {
+                    "$ref": "#/definitions/spell/definitions/school"
+                }
+            }
+        }
+    },
+    "properties": {
+        "spell": {
+            "$ref": "#/definitions/spell"
+        }
+    }
+}`
+
+func TestTemplateTypesWithEnum(t *testing.T) {
+	schema := getSchemaString(t, enumSpellsSchema)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx := &Context{
+		Prgm:                "dispel",
+		PkgName:             "handler",
+		Routes:              routes,
+		HandlerReceiverType: "*App",
+	}
+
+	expectedOut, err := format.Source([]byte(fmt.Sprintf(`// generated by %s; DO NOT EDIT
+
+package %s
+
+import (
+    "encoding/json"
+    "fmt"
+)
+
+// Spell represents the data structure sent/received on the following routes:
+//
+//  * Request body of POST /schools/{spell-school}/spells
+type Spell struct {
+    School SpellSchool `+"`"+`json:"school"`+"`"+`
+}
+
+// SpellSchool enumerates the values allowed by the JSON Schema.
+type SpellSchool string
+
+// Values of SpellSchool.
+const (
+    SpellSchoolFire SpellSchool = "fire"
+    SpellSchoolDarkArts SpellSchool = "dark-arts"
+)
+
+// IsValid returns true if ss is one of the values of SpellSchool.
+func (ss SpellSchool) IsValid() bool {
+    switch ss {
+    case SpellSchoolFire, SpellSchoolDarkArts:
+        return true
+    }
+    return false
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It fails if the JSON string is not one of the values of SpellSchool.
+func (ss *SpellSchool) UnmarshalJSON(data []byte) error {
+    var s string
+    if err := json.Unmarshal(data, &s); err != nil {
+        return err
+    }
+    if !SpellSchool(s).IsValid() {
+        return fmt.Errorf("invalid SpellSchool value %%q", s)
+    }
+    *ss = SpellSchool(s)
+    return nil
+}
+`, ctx.Prgm, ctx.PkgName)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tmpl, err := NewTemplate(sp, typesTmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Generate(&buf, ctx); err != nil {
+		t.Error(err)
+		return
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Log(buf.String())
+		t.Error(err)
+		return
+	}
+	if string(expectedOut) != string(out) {
+		t.Errorf("expected %#v, got %#v", string(expectedOut), string(out))
+		return
+	}
+}
+
+func TestTemplateHandlersWithEnumRouteParam(t *testing.T) {
+	schema := getSchemaString(t, enumSpellsSchema)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx := &Context{
+		Prgm:                "dispel",
+		PkgName:             "handler",
+		Routes:              routes,
+		HandlerReceiverType: "*App",
+	}
+
+	tmpl, err := NewTemplate(sp, handlersTmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Generate(&buf, ctx); err != nil {
+		t.Error(err)
+		return
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Log(buf.String())
+		t.Error(err)
+		return
+	}
+	expectedCheck := `			spellSchool := SpellSchool(spellSchoolParam)
+			if !spellSchool.IsValid() {
+				return http.StatusBadRequest, errors.New("invalid route parameter \"spell-school\"")
+			}
+			var vreq Spell
+			if err := hd.Decode(w, r, &vreq); err != nil {
+				return http.StatusBadRequest, err
+			}
+			status, err := a.postSchoolsOneSpells(w, r, spellSchool, &vreq)
+`
+	if !bytes.Contains(out, []byte(expectedCheck)) {
+		t.Errorf("expected %q in %s", expectedCheck, out)
+	}
+}
+
+func TestTemplateTypesWithDescriptions(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "description": "A spell is cast by a wizard.\n\nIts power depends on its level.",
+            "definitions": {
+                "name": {"type": "string", "description": "unique name of the spell"},
+                "school": {"type": "string", "enum": ["fire", "water"], "description": "The school of magic of a spell."}
+            },
+            "properties": {
+                "name": {"$ref": "#/definitions/spell/definitions/name"},
+                "school": {"$ref": "#/definitions/spell/definitions/school", "description": "school of the spell"},
+                "level": {"type": "integer"}
+            },
+            "required": ["name"],
+            "links": [
+                {
+                    "href": "/spells",
+                    "method": "POST",
+                    "rel": "create",
+                    "schema": {"$ref": "#/definitions/spell"}
+                }
+            ]
+        }
+    },
+    "properties": {
+        "spell": {"$ref": "#/definitions/spell"}
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx := &Context{
+		Prgm:                "dispel",
+		PkgName:             "handler",
+		Routes:              routes,
+		HandlerReceiverType: "*App",
+	}
+
+	tmpl, err := NewTemplate(sp, typesTmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Generate(&buf, ctx); err != nil {
+		t.Error(err)
+		return
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Log(buf.String())
+		t.Error(err)
+		return
+	}
+	expectedChecks := []string{
+		`//   - Request body of POST /spells
+//
+// A spell is cast by a wizard.
+//
+// Its power depends on its level.
+type Spell struct {
+	// unique name of the spell
+	Name string ` + "`json:\"name\"`" + `
+	// school of the spell
+	School *SpellSchool ` + "`json:\"school,omitempty\"`" + `
+	Level  *int         ` + "`json:\"level,omitempty\"`" + `
+}`,
+		`// SpellSchool enumerates the values allowed by the JSON Schema.
+//
+// The school of magic of a spell.
+type SpellSchool string`,
+	}
+	for _, expectedCheck := range expectedChecks {
+		if !bytes.Contains(out, []byte(expectedCheck)) {
+			t.Errorf("expected %q in %s", expectedCheck, out)
+		}
+	}
+}
+
+func TestTemplateTypesWithAllOf(t *testing.T) {
+	schema := getSchemaString(t, allOfSpellsSchema)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema, SortFields: true}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx := &Context{
+		Prgm:                "dispel",
+		PkgName:             "handler",
+		Routes:              routes,
+		HandlerReceiverType: "*App",
+	}
+
+	expectedOut, err := format.Source([]byte(fmt.Sprintf(`// generated by %s; DO NOT EDIT
+
+package %s
+
+import "unicode/utf8"
+
+// BaseResource represents the data structure sent/received on the following routes:
+//
+type BaseResource struct {
+    Etag *string `+"`"+`json:"etag,omitempty"`+"`"+`
+    ID string `+"`"+`json:"id"`+"`"+`
+}
+
+// Spell represents the data structure sent/received on the following routes:
+//
+//  * Request body of POST /spells
+type Spell struct {
+    BaseResource
+    Element *string `+"`"+`json:"element,omitempty"`+"`"+`
+    Name string `+"`"+`json:"name"`+"`"+`
+    Power *int `+"`"+`json:"power,omitempty"`+"`"+`
+}
+
+// Validate checks the Spell against the constraints of its JSON Schema.
+func (s *Spell) Validate() error {
+    var errs ValidationErrors
+    if utf8.RuneCountInString(s.Name) > 10 {
+        errs = append(errs, ValidationError{Field: "name", Msg: "must be at most 10 characters long"})
+    }
+    if len(errs) > 0 {
+        return errs
+    }
+    return nil
+}
+
+%s
+`, ctx.Prgm, ctx.PkgName, validationErrorsDecl)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tmpl, err := NewTemplate(sp, typesTmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Generate(&buf, ctx); err != nil {
+		t.Error(err)
+		return
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Log(buf.String())
+		t.Error(err)
+		return
+	}
+	if string(expectedOut) != string(out) {
+		t.Errorf("expected %#v, got %#v", string(expectedOut), string(out))
+		return
+	}
+}
+
 func TestTemplateTypesCompositeResources(t *testing.T) {
 	schema := getSchema(t, "testdata/rpg.json")
 	if t.Failed() {
 		return
 	}
-	sp := &SchemaParser{RootSchema: schema}
+	sp := &SchemaParser{RootSchema: schema, SortFields: true}
 	routes, err := sp.ParseRoutes()
 	if err != nil {
 		t.Error(err)
@@ -542,24 +1019,24 @@ package %s
 //  * Response body of POST /characters
 //  * Response body of GET /characters/{character-name}
 type Character struct {
-    Level int   `+"`"+`json:"level"`+"`"+`
-    Name string    `+"`"+`json:"name"`+"`"+`
-    Spells []Spell   `+"`"+`json:"spells"`+"`"+`
+    Level *int   `+"`"+`json:"level,omitempty"`+"`"+`
+    Name *string    `+"`"+`json:"name,omitempty"`+"`"+`
+    Spells []Spell   `+"`"+`json:"spells,omitempty"`+"`"+`
 }
 
 // CreateCharacterIn represents the data structure sent/received on the following routes:
 //
 //  * Request body of POST /characters
 type CreateCharacterIn struct {
-    Name string    `+"`"+`json:"name"`+"`"+`
+    Name *string    `+"`"+`json:"name,omitempty"`+"`"+`
 }
 
 // ListCharacterOutOne represents the data structure sent/received on the following routes:
 //
 //  * Response body of GET /characters (as []ListCharacterOutOne)
 type ListCharacterOutOne struct {
-    Level int   `+"`"+`json:"level"`+"`"+`
-    Name string    `+"`"+`json:"name"`+"`"+`
+    Level *int   `+"`"+`json:"level,omitempty"`+"`"+`
+    Name *string    `+"`"+`json:"name,omitempty"`+"`"+`
 }
 
 // Spell represents the data structure sent/received on the following routes:
@@ -569,9 +1046,9 @@ type ListCharacterOutOne struct {
 //  * Response body of POST /spells
 //  * Response body of GET /spells/{spell-name}
 type Spell struct {
-    Element string `+"`"+`json:"element"`+"`"+`
-    Name string    `+"`"+`json:"name"`+"`"+`
-    Power int   `+"`"+`json:"power"`+"`"+`
+    Element *string `+"`"+`json:"element,omitempty"`+"`"+`
+    Name *string    `+"`"+`json:"name,omitempty"`+"`"+`
+    Power *int   `+"`"+`json:"power,omitempty"`+"`"+`
 }
 
 `, ctx.Prgm, ctx.PkgName)))
@@ -831,6 +1308,49 @@ func TestSchemaHasRedefinedTypes(t *testing.T) {
 	}
 }
 
+func TestSchemaHasRedefinedInlineObjects(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "weapon": {
+            "properties": {
+                "kind": {"type": "string", "enum": ["weapon"]},
+                "stats": {"properties": {"damage": {"type": "integer"}}},
+                "tags": {"type": "array", "items": {"type": "string"}}
+            },
+            "links": [
+                {"href": "/weapons", "method": "POST", "rel": "create", "schema": {"$ref": "#/definitions/weapon"}}
+            ]
+        },
+        "armor": {
+            "properties": {
+                "kind": {"type": "string", "enum": ["armor"]},
+                "stats": {"properties": {"defense": {"type": "integer"}}},
+                "tags": {"type": "array", "items": {"type": "integer"}}
+            },
+            "links": [
+                {"href": "/armors", "method": "POST", "rel": "create", "schema": {"$ref": "#/definitions/armor"}}
+            ]
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	// The inline enums are named WeaponKind and ArmorKind, the inline objects are both named Stats.
+	sp := &SchemaParser{RootSchema: schema}
+	_, err := sp.ParseRoutes()
+	e, ok := err.(*TypeRedefinitionError)
+	if !ok {
+		t.Errorf("expected a *TypeRedefinitionError, got %#v", err)
+		return
+	}
+	if e.Name != "Stats" || len(e.Redefs) != 1 {
+		t.Errorf("expected Stats to be redefined once, got %s redefined %d times", e.Name, len(e.Redefs))
+	}
+}
+
 func TestTemplateHandlerFuncsAlreadyDefined(t *testing.T) {
 	schema := getSchema(t, "testdata/spells.json")
 	if t.Failed() {
@@ -860,6 +1380,8 @@ import (
 )
 
 // getSpellsOne is the handler for GET /spells/{spell-name}.
+//
+// Info for a spell.
 func (a *App) getSpellsOne(w http.ResponseWriter, r *http.Request, spellName string) (int, *Spell, error) {
     return http.StatusNotImplemented, nil, nil
 }
@@ -898,7 +1420,7 @@ func TestTemplateTypesCompositeResourcesAlreadyDefined(t *testing.T) {
 	if t.Failed() {
 		return
 	}
-	sp := &SchemaParser{RootSchema: schema}
+	sp := &SchemaParser{RootSchema: schema, SortFields: true}
 	routes, err := sp.ParseRoutes()
 	if err != nil {
 		t.Error(err)
@@ -921,15 +1443,15 @@ package %s
 //
 //  * Request body of POST /characters
 type CreateCharacterIn struct {
-    Name string    `+"`"+`json:"name"`+"`"+`
+    Name *string    `+"`"+`json:"name,omitempty"`+"`"+`
 }
 
 // ListCharacterOutOne represents the data structure sent/received on the following routes:
 //
 //  * Response body of GET /characters (as []ListCharacterOutOne)
 type ListCharacterOutOne struct {
-    Level int   `+"`"+`json:"level"`+"`"+`
-    Name string    `+"`"+`json:"name"`+"`"+`
+    Level *int   `+"`"+`json:"level,omitempty"`+"`"+`
+    Name *string    `+"`"+`json:"name,omitempty"`+"`"+`
 }
 
 // Spell represents the data structure sent/received on the following routes:
@@ -939,9 +1461,9 @@ type ListCharacterOutOne struct {
 //  * Response body of POST /spells
 //  * Response body of GET /spells/{spell-name}
 type Spell struct {
-    Element string `+"`"+`json:"element"`+"`"+`
-    Name string    `+"`"+`json:"name"`+"`"+`
-    Power int   `+"`"+`json:"power"`+"`"+`
+    Element *string `+"`"+`json:"element,omitempty"`+"`"+`
+    Name *string    `+"`"+`json:"name,omitempty"`+"`"+`
+    Power *int   `+"`"+`json:"power,omitempty"`+"`"+`
 }
 
 `, ctx.Prgm, ctx.PkgName)))
@@ -1064,11 +1586,11 @@ func registerHandlers(hr HandlerRegisterer, rpg RouteParamGetter, a *App, hd HTT
 	})
 	hr.RegisterHandler(routeFilesOne, &MethodHandler{
 		Get: ehhf(func(w http.ResponseWriter, r *http.Request) (int, error) {
-			fileId := rpg.GetRouteParam(r, "file-id")
-			if fileId == "" {
+			fileID := rpg.GetRouteParam(r, "file-id")
+			if fileID == "" {
 				return http.StatusBadRequest, errors.New("empty route parameter \"file-id\"")
 			}
-			status, err := a.getFilesOne(w, r, fileId)
+			status, err := a.getFilesOne(w, r, fileID)
 			if err != nil {
 				return status, err
 			}
@@ -1132,17 +1654,23 @@ import (
 )
 
 // getFiles is the handler for GET /files.
+//
+// List existing files.
 func (a *App) getFiles(w http.ResponseWriter, r *http.Request) (int, []File, error) {
 	return http.StatusNotImplemented, nil, nil
 }
 
 // postFiles is the handler for POST /files.
+//
+// Create a new file using a raw binary body.
 func (a *App) postFiles(w http.ResponseWriter, r *http.Request) (int, *File, error) {
 	return http.StatusNotImplemented, nil, nil
 }
 
 // getFilesOne is the handler for GET /files/{file-id}.
-func (a *App) getFilesOne(w http.ResponseWriter, r *http.Request, fileId string) (int, error) {
+//
+// Binary data of an existing file.
+func (a *App) getFilesOne(w http.ResponseWriter, r *http.Request, fileID string) (int, error) {
 	http.Error(w, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)
 	return http.StatusNotImplemented, nil
 }
@@ -3,5 +3,5 @@
 package dispel
 
 var handlerfuncsTmpl = tmpl(asset.init(asset{Name: "handlerfuncs.go.tmpl", Content: "" +
-	"// generated by {{ .Prgm }}; DO NOT EDIT\n\npackage {{ .PkgName }}\n\n{{ if allHandlerFuncsImplemented }}// No default handler func was generated, because all are implemented.\n{{ else }}import (\n\t\"net/http\"\n)\n\n{{/* Generate a function for each method+resource */}}\n{{ $handlerReceiverType := .HandlerReceiverType }}{{ $existingHandlers := .ExistingHandlers }}{{ range .Routes.ByResource }}{{ $route := . }}{{ range .Methods }}{{ $io := index $route.MethodRouteIOMap . }}{{/*\n*/}}{{ with $funcName := (handlerFuncName . $route.Name) }}{{/*\nDo not generate the handler if it's already present in the package\n*/}}{{ if not (hasItem $existingHandlers $funcName) }}{{/*\n*/}}// {{ $funcName }} is the handler for {{ $io.Method }} {{ $route.Path }}.\nfunc ({{ varname $handlerReceiverType }} {{ $handlerReceiverType }}) {{ $funcName }}(w http.ResponseWriter, r *http.Request{{ range $route.RouteParams }}, {{ .Varname }} string{{end}}{{/*\nGenerate in and out types*/}}{{ if $io.InType }}, vreq {{ printSmartDerefType $io.InType }}{{end}}) (int{{ if $io.OutType }}, {{ printSmartDerefType $io.OutType }}{{end}}, error) {\n\t{{ if $io.OutputIsNotJSON }}http.Error(w, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)\n{{ end }}\treturn http.StatusNotImplemented{{ if $io.OutType }}, nil{{end}}, nil\n}\n\n{{end}}{{end}}{{end}}{{end}}\n{{ end }}\n" +
+	"// generated by {{ .Prgm }}; DO NOT EDIT\n\npackage {{ .PkgName }}\n\n{{ if allHandlerFuncsImplemented }}// No default handler func was generated, because all are implemented.\n{{ else }}import ({{ range handlerFuncsImports }}\n\t\"{{ . }}\"{{ end }}\n)\n\n{{/* Generate a function for each method+resource */}}\n{{ $handlerReceiverType := .HandlerReceiverType }}{{ $existingHandlers := .ExistingHandlers }}{{ range .Routes.ByResource }}{{ $route := . }}{{ range .Methods }}{{ $io := index $route.MethodRouteIOMap . }}{{/*\n*/}}{{ with $funcName := (handlerFuncName . $route.Name) }}{{/*\nDo not generate the handler if it's already present in the package\n*/}}{{ if not (hasItem $existingHandlers $funcName) }}{{/*\n*/}}// {{ $funcName }} is the handler for {{ $io.Method }} {{ $route.Path }}.\n{{ printLinkDoc $io.Link }}func ({{ varname $handlerReceiverType }} {{ $handlerReceiverType }}) {{ $funcName }}(w http.ResponseWriter, r *http.Request{{ range $route.RouteParams }}, {{ .Varname }} {{ routeParamGoType . }}{{end}}{{/*\nGenerate in and out types*/}}{{ if $io.QueryType }}, query {{ printTypeName $io.QueryType }}{{end}}{{ if $io.InType }}, vreq {{ printSmartDerefType $io.InType }}{{end}}) (int{{ if $io.OutType }}, {{ printSmartDerefType $io.OutType }}{{end}}, error) {\n\t{{ if $io.OutputIsNotJSON }}http.Error(w, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)\n{{ end }}\treturn http.StatusNotImplemented{{ if $io.OutType }}, nil{{end}}, nil\n}\n\n{{end}}{{end}}{{end}}{{end}}\n{{ end }}\n" +
 	""}))
@@ -2,10 +2,12 @@ package dispel
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
 	"unicode"
@@ -13,56 +15,343 @@ import (
 )
 
 // Schema represents a JSON Hyper Schema.
+//
+// It holds the keywords of draft-04 to draft 2020-12; those whose meaning changed between drafts
+// are interpreted according to the draft of its document, see Draft.
 type Schema struct {
+	// ID is the identifier of a draft-04 schema, DollarID the one of the later drafts.
 	ID          string `json:"id,omitempty"`
+	DollarID    string `json:"$id,omitempty"`
 	Title       string `json:"title,omitempty"`
 	Description string `json:"description,omitempty"`
 	Version     string `json:"version,omitempty"`
 
-	Default  interface{} `json:"default,omitempty"`
-	ReadOnly bool        `json:"readOnly,omitempty"` // unsupported
-	Example  interface{} `json:"example,omitempty"`
-	Format   string      `json:"format,omitempty"` // unsupported
+	Default  interface{}   `json:"default,omitempty"`
+	ReadOnly bool          `json:"readOnly,omitempty"`
+	Example  interface{}   `json:"example,omitempty"`
+	Examples []interface{} `json:"examples,omitempty"`
+	Format   string        `json:"format,omitempty"`
 
-	Type string `json:"type,omitempty"`
+	Type SchemaType `json:"type,omitempty"`
 
-	Ref    string `json:"$ref,omitempty"`
-	Schema string `json:"$schema,omitempty"` // unsupported
+	Ref string `json:"$ref,omitempty"`
+	// Schema is the URI of the draft of the document, see Draft.
+	Schema string `json:"$schema,omitempty"`
 
 	Definitions map[string]*Schema `json:"definitions,omitempty"`
-
-	MultipleOf       float64 `json:"multipleOf,omitempty"`       // unsupported
-	Maximum          float64 `json:"maximum,omitempty"`          // unsupported
-	ExclusiveMaximum bool    `json:"exclusiveMaximum,omitempty"` // unsupported
-	Minimum          float64 `json:"minimum,omitempty"`          // unsupported
-	ExclusiveMinimum bool    `json:"exclusiveMinimum,omitempty"` // unsupported
-
-	MinLength int    `json:"minLength,omitempty"` // unsupported
-	MaxLength int    `json:"maxLength,omitempty"` // unsupported
-	Pattern   string `json:"pattern,omitempty"`   // unsupported
+	Defs        map[string]*Schema `json:"$defs,omitempty"`
+
+	MultipleOf float64  `json:"multipleOf,omitempty"`
+	Maximum    *float64 `json:"maximum,omitempty"`
+	Minimum    *float64 `json:"minimum,omitempty"`
+	// ExclusiveMaximum and ExclusiveMinimum are the boolean keywords of draft-04,
+	// ExclusiveMaximumValue and ExclusiveMinimumValue the numeric ones of the later drafts.
+	ExclusiveMaximum      bool     `json:"-"`
+	ExclusiveMinimum      bool     `json:"-"`
+	ExclusiveMaximumValue *float64 `json:"-"`
+	ExclusiveMinimumValue *float64 `json:"-"`
+
+	MinLength int    `json:"minLength,omitempty"`
+	MaxLength int    `json:"maxLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
 
 	MinProperties        int                    `json:"minProperties,omitempty"` // unsupported
 	MaxProperties        int                    `json:"maxProperties,omitempty"` // unsupported
-	Required             []string               `json:"required,omitempty"`      // unsupported
+	Required             []string               `json:"required,omitempty"`
 	Properties           map[string]*Schema     `json:"properties,omitempty"`
-	Dependencies         map[string]interface{} `json:"dependencies,omitempty"`         // unsupported
-	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"` // unsupported
-	PatternProperties    map[string]*Schema     `json:"patternProperties,omitempty"`    // unsupported
+	Dependencies         map[string]interface{} `json:"dependencies,omitempty"` // unsupported
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	PatternProperties    map[string]*Schema     `json:"patternProperties,omitempty"`
 
 	Items           *Schema     `json:"items,omitempty"`
-	MinItems        int         `json:"minItems,omitempty"`        // unsupported
-	MaxItems        int         `json:"maxItems,omitempty"`        // unsupported
-	UniqueItems     bool        `json:"uniqueItems,omitempty"`     // unsupported
+	MinItems        int         `json:"minItems,omitempty"`
+	MaxItems        int         `json:"maxItems,omitempty"`
+	UniqueItems     bool        `json:"uniqueItems,omitempty"`
 	AdditionalItems interface{} `json:"additionalItems,omitempty"` // unsupported
 
-	Enum []string `json:"enum,omitempty"` // unsupported
+	Enum  []string    `json:"enum,omitempty"`
+	Const interface{} `json:"const,omitempty"`
+
+	OneOf []Schema `json:"oneOf,omitempty"`
+	AnyOf []Schema `json:"anyOf,omitempty"`
+	AllOf []Schema `json:"allOf,omitempty"`
+	Not   *Schema  `json:"not,omitempty"` // unsupported
 
-	OneOf []Schema `json:"oneOf,omitempty"` // unsupported
-	AnyOf []Schema `json:"anyOf,omitempty"` // unsupported
-	AllOf []Schema `json:"allOf,omitempty"` // unsupported
-	Not   *Schema  `json:"not,omitempty"`   // unsupported
+	// Discriminator is the property of the oneOf/anyOf variants which tells them apart.
+	Discriminator string `json:"x-discriminator,omitempty"`
+	// GoName overrides the name of the Go type of the schema, and of the struct field of a property.
+	GoName string `json:"x-go-name,omitempty"`
 
 	Links []Link `json:"links,omitempty"`
+
+	// propertyOrder, definitionOrder and defsOrder hold the names of the properties, definitions
+	// and $defs, in the order of the schema document.
+	propertyOrder   []string
+	definitionOrder []string
+	defsOrder       []string
+	// unknownKeywords holds the keywords of the schema document which aren't fields of Schema.
+	unknownKeywords []string
+	// locations holds the location of the nodes of a schema document read by ParseSchema, by JSON pointer.
+	// Only the root schema of the document has them.
+	locations map[string]Location
+	// keywordSchemas holds the schemas decoded from the keywords which may hold other values,
+	// like additionalProperties, by relative JSON pointer.
+	keywordSchemas map[string]*Schema
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It records the order of the properties, definitions and $defs, which their maps don't keep,
+// and decodes the schemas of the keywords which may hold another value, like additionalProperties.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	// plain has the fields of Schema, but not its methods.
+	type plain Schema
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+	var raw struct {
+		Properties       json.RawMessage `json:"properties"`
+		Definitions      json.RawMessage `json:"definitions"`
+		Defs             json.RawMessage `json:"$defs"`
+		ExclusiveMaximum json.RawMessage `json:"exclusiveMaximum"`
+		ExclusiveMinimum json.RawMessage `json:"exclusiveMinimum"`
+		Enum             []*string       `json:"enum"`
+		// The keywords which may hold a schema, or another value.
+		AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+		AdditionalItems      json.RawMessage            `json:"additionalItems"`
+		Dependencies         map[string]json.RawMessage `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	// The null value of an enum isn't the "" it decodes to in Enum: it's dropped,
+	// a nullable type accepts it.
+	if raw.Enum != nil {
+		s.Enum = nil
+		for _, v := range raw.Enum {
+			if v != nil {
+				s.Enum = append(s.Enum, *v)
+			}
+		}
+	}
+	var err error
+	if s.propertyOrder, err = objectKeys(raw.Properties); err != nil {
+		return err
+	}
+	if s.definitionOrder, err = objectKeys(raw.Definitions); err != nil {
+		return err
+	}
+	if s.defsOrder, err = objectKeys(raw.Defs); err != nil {
+		return err
+	}
+	if err := unmarshalExclusive(raw.ExclusiveMaximum, &s.ExclusiveMaximum, &s.ExclusiveMaximumValue); err != nil {
+		return fmt.Errorf("exclusiveMaximum: %v", err)
+	}
+	if err := unmarshalExclusive(raw.ExclusiveMinimum, &s.ExclusiveMinimum, &s.ExclusiveMinimumValue); err != nil {
+		return fmt.Errorf("exclusiveMinimum: %v", err)
+	}
+	if err := s.decodeKeywordSchema("/additionalProperties", raw.AdditionalProperties); err != nil {
+		return fmt.Errorf("additionalProperties: %v", err)
+	}
+	if err := s.decodeKeywordSchema("/additionalItems", raw.AdditionalItems); err != nil {
+		return fmt.Errorf("additionalItems: %v", err)
+	}
+	for name, v := range raw.Dependencies {
+		if err := s.decodeKeywordSchema("/dependencies/"+pointerToken(name), v); err != nil {
+			return fmt.Errorf("dependencies: %v", err)
+		}
+	}
+	keywords, err := objectKeys(data)
+	if err != nil {
+		return err
+	}
+	for _, keyword := range keywords {
+		if _, ok := schemaKeywordFields[keyword]; !ok && keyword != "exclusiveMaximum" && keyword != "exclusiveMinimum" {
+			s.unknownKeywords = append(s.unknownKeywords, keyword)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// It encodes the exclusiveMaximum and exclusiveMinimum keywords in their boolean or numeric form.
+func (s Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+	v := struct {
+		plain
+		ExclusiveMaximum interface{} `json:"exclusiveMaximum,omitempty"`
+		ExclusiveMinimum interface{} `json:"exclusiveMinimum,omitempty"`
+	}{plain: plain(s)}
+	if s.ExclusiveMaximumValue != nil {
+		v.ExclusiveMaximum = *s.ExclusiveMaximumValue
+	} else if s.ExclusiveMaximum {
+		v.ExclusiveMaximum = true
+	}
+	if s.ExclusiveMinimumValue != nil {
+		v.ExclusiveMinimum = *s.ExclusiveMinimumValue
+	} else if s.ExclusiveMinimum {
+		v.ExclusiveMinimum = true
+	}
+	return json.Marshal(v)
+}
+
+// unmarshalExclusive decodes the value of the exclusiveMaximum or exclusiveMinimum keyword,
+// which is a boolean in draft-04 and a number in the later drafts.
+func unmarshalExclusive(data json.RawMessage, flag *bool, value **float64) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, flag); err == nil {
+		return nil
+	}
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("must be a boolean or a number, got %s", data)
+	}
+	*value = &f
+	return nil
+}
+
+// PropertyNames returns the names of the properties of s, in the order of the schema document.
+// The properties whose order is unknown, e.g those added after decoding s, are last, sorted by name.
+func (s *Schema) PropertyNames() []string {
+	return orderedKeys(s.Properties, s.propertyOrder)
+}
+
+// DefinitionNames returns the names of the definitions of s, in the order of the schema document.
+// The definitions whose order is unknown, e.g those added after decoding s, are last, sorted by name.
+func (s *Schema) DefinitionNames() []string {
+	return orderedKeys(s.Definitions, s.definitionOrder)
+}
+
+// DefsNames returns the names of the $defs of s, in the order of the schema document.
+// The $defs whose order is unknown, e.g those added after decoding s, are last, sorted by name.
+func (s *Schema) DefsNames() []string {
+	return orderedKeys(s.Defs, s.defsOrder)
+}
+
+// AdditionalPropertiesSchema returns the schema of the additionalProperties of s,
+// or nil if they're not described by a schema, e.g if additionalProperties is a boolean.
+// The schema is decoded once: the same one is returned each time.
+func (s *Schema) AdditionalPropertiesSchema() (*Schema, error) {
+	return s.keywordSchema("/additionalProperties", s.AdditionalProperties)
+}
+
+// decodeKeywordSchema decodes the schema of the keyword at the relative JSON pointer p from its JSON value data,
+// if it's an object, like the other schemas of s are: the order of its properties is kept.
+func (s *Schema) decodeKeywordSchema(p string, data json.RawMessage) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return nil
+	}
+	var sub Schema
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return err
+	}
+	if s.keywordSchemas == nil {
+		s.keywordSchemas = make(map[string]*Schema)
+	}
+	s.keywordSchemas[p] = &sub
+	return nil
+}
+
+// keywordSchema returns the schema decoded from v, the value of s at the relative JSON pointer p,
+// or nil if v isn't a JSON object.
+// The schemas of a decoded document are decoded with it; those of a Schema built in Go are decoded once.
+func (s *Schema) keywordSchema(p string, v interface{}) (*Schema, error) {
+	if sub, ok := s.keywordSchemas[p]; ok {
+		return sub, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var sub Schema
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return nil, err
+	}
+	if s.keywordSchemas == nil {
+		s.keywordSchemas = make(map[string]*Schema)
+	}
+	s.keywordSchemas[p] = &sub
+	return &sub, nil
+}
+
+// orderedKeys returns the keys of m listed in order, followed by the others sorted.
+func orderedKeys(m map[string]*Schema, order []string) []string {
+	keys := make([]string, 0, len(m))
+	listed := make(map[string]bool, len(order))
+	for _, k := range order {
+		if _, ok := m[k]; ok && !listed[k] {
+			listed[k] = true
+			keys = append(keys, k)
+		}
+	}
+	var others []string
+	for k := range m {
+		if !listed[k] {
+			others = append(others, k)
+		}
+	}
+	sort.Strings(others)
+	return append(keys, others...)
+}
+
+// objectKeys returns the keys of the JSON object data in order, or nil if data is not an object.
+func objectKeys(data json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, tok.(string))
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// SchemaType represents the type keyword of a Schema, which is either a single type or a list of types.
+type SchemaType []string
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It accepts a string as well as an array of strings.
+func (st *SchemaType) UnmarshalJSON(data []byte) error {
+	var typ string
+	if err := json.Unmarshal(data, &typ); err == nil {
+		*st = SchemaType{typ}
+		return nil
+	}
+	var types []string
+	if err := json.Unmarshal(data, &types); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings, got %s", data)
+	}
+	*st = types
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// A single type is encoded as a string.
+func (st SchemaType) MarshalJSON() ([]byte, error) {
+	if len(st) == 1 {
+		return json.Marshal(st[0])
+	}
+	return json.Marshal([]string(st))
+}
+
+// String returns the type, or the comma-separated list of types.
+func (st SchemaType) String() string {
+	return strings.Join(st, ",")
 }
 
 // Link represents a Link description.
@@ -76,10 +365,33 @@ type Link struct {
 	TargetSchema *Schema `json:"targetSchema,omitempty"`
 	EncType      string  `json:"encType,omitempty"`
 	MediaType    string  `json:"mediaType,omitempty"`
+	// HRefSchema describes the variables of the href, as its properties.
+	HRefSchema *Schema `json:"hrefSchema,omitempty"`
+	// TargetHints holds hints about the target resource, like the methods it allows.
+	TargetHints map[string]interface{} `json:"targetHints,omitempty"`
+	// SubmissionSchema and SubmissionMediaType are the draft-07 names of Schema and EncType.
+	SubmissionSchema    *Schema `json:"submissionSchema,omitempty"`
+	SubmissionMediaType string  `json:"submissionMediaType,omitempty"`
+	// GoName overrides the name of the handler func of the link.
+	GoName string `json:"x-go-name,omitempty"`
 }
 
 // ApplyDefaults applies default values to the Link's fields.
+//
+// The fields of the draft-07 links are copied to their draft-04 counterparts when they're unset.
+// A link without method gets the method listed by the allow target hint, if it lists only one.
 func (l *Link) ApplyDefaults() {
+	if l.Schema == nil {
+		l.Schema = l.SubmissionSchema
+	}
+	if l.EncType == "" {
+		l.EncType = l.SubmissionMediaType
+	}
+	if l.Method == "" {
+		if allow := l.AllowedMethods(); len(allow) == 1 {
+			l.Method = allow[0]
+		}
+	}
 	if l.EncType == "" {
 		l.EncType = "application/json"
 	}
@@ -88,6 +400,22 @@ func (l *Link) ApplyDefaults() {
 	}
 }
 
+// AllowedMethods returns the methods listed by the allow target hint of the Link.
+func (l Link) AllowedMethods() []string {
+	var methods []string
+	switch allow := l.TargetHints["allow"].(type) {
+	case string:
+		methods = append(methods, strings.ToUpper(allow))
+	case []interface{}:
+		for _, m := range allow {
+			if m, ok := m.(string); ok {
+				methods = append(methods, strings.ToUpper(m))
+			}
+		}
+	}
+	return methods
+}
+
 // ReceivesJSON returns true if the EncType of the Link is recognized as json.
 func (l Link) ReceivesJSON() bool {
 	return strings.HasPrefix(l.EncType, "application/json")
@@ -98,6 +426,16 @@ func (l Link) SendsJSON() bool {
 	return strings.HasPrefix(l.MediaType, "application/json")
 }
 
+// SchemaDescribesQuery returns true if the Schema of the Link describes the query string
+// rather than the request body, which is the case of GET and DELETE links.
+func (l Link) SchemaDescribesQuery() bool {
+	switch strings.ToUpper(l.Method) {
+	case "GET", "DELETE":
+		return true
+	}
+	return false
+}
+
 // Route represents an HTTP endpoint for a resource, with JSON on the wire.
 type Route struct {
 	Path        string
@@ -116,6 +454,8 @@ type RouteIO struct {
 	InType JSONType
 	// OutType is the JSON type coming out.
 	OutType JSONType
+	// QueryType is the JSON object whose fields are the parameters of the query string, if any.
+	QueryType JSONType
 }
 
 // RouteParam represents a variable chunk in an HTTP endpoint path.
@@ -162,11 +502,25 @@ func (routes Routes) walkType(typ JSONType, walkFn func(JSONType)) {
 	walkFn(typ)
 	switch j := typ.(type) {
 	case JSONObject:
+		for _, e := range j.Embedded {
+			routes.walkType(e, walkFn)
+		}
 		for _, field := range j.Fields {
 			routes.walkType(field.Type, walkFn)
 		}
+		if j.AdditionalProperties != nil {
+			routes.walkType(j.AdditionalProperties, walkFn)
+		}
 	case JSONArray:
 		routes.walkType(j.Items, walkFn)
+	case JSONMap:
+		routes.walkType(j.Values, walkFn)
+	case JSONUnion:
+		for _, variant := range j.Variants {
+			routes.walkType(variant, walkFn)
+		}
+	case JSONNullable:
+		routes.walkType(j.Value, walkFn)
 	}
 }
 
@@ -176,7 +530,14 @@ func (routes Routes) JSONNamedTypes() []JSONTypeNamer {
 	var a []JSONTypeNamer
 
 	for _, route := range routes {
-		for _, typ := range []JSONType{route.InType, route.OutType} {
+		types := []JSONType{route.InType, route.OutType, route.QueryType}
+		for _, rp := range route.RouteParams {
+			// Only enums are generated as named types for route params.
+			if _, ok := rp.Type.(JSONEnum); ok {
+				types = append(types, rp.Type)
+			}
+		}
+		for _, typ := range types {
 			if typ == nil {
 				continue
 			}
@@ -258,6 +619,16 @@ type ResourceRoute struct {
 	MethodRouteIOMap MethodRouteIOMap
 }
 
+// HasQuery returns true if one of the methods of the resource has query string parameters.
+func (resourceRoutes *ResourceRoute) HasQuery() bool {
+	for _, rioal := range resourceRoutes.MethodRouteIOMap {
+		if rioal.QueryType != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // Methods lists the available HTTP methods on the resource.
 func (resourceRoutes *ResourceRoute) Methods() Methods {
 	var methods Methods
@@ -275,9 +646,18 @@ func (r ResourceRoutes) Len() int           { return len(r) }
 func (r ResourceRoutes) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
 func (r ResourceRoutes) Less(i, j int) bool { return r[i].Name < r[j].Name }
 
+// isQueryHRefVar returns true if the href variable v is a form-style query expansion,
+// e.g {?page,per_page}.
+func isQueryHRefVar(v string) bool {
+	return strings.HasPrefix(v, "{?") || strings.HasPrefix(v, "{&")
+}
+
 func href2path(href string) (string, error) {
 	var firstErr error
 	p, err := mapHRefVar(href, func(v string) string {
+		if isQueryHRefVar(v) {
+			return ""
+		}
 		if v[0] != '{' && v[len(v)-1] != '}' {
 			return ""
 		}
@@ -290,11 +670,7 @@ func href2path(href string) (string, error) {
 			return ""
 		}
 
-		// Strip leading #/definitions/
-		v = strings.Replace(string(uv), "#/definitions/", "", 1)
-		// Hyphenify the remaining ones
-		// TODO allow customize this
-		v = strings.Replace(v, "/definitions/", "-", -1)
+		v = definitionsPathName(string(uv))
 
 		return fmt.Sprintf("{%s}", v)
 	})
@@ -311,6 +687,9 @@ func href2name(href string) (string, error) {
 	const varnameRepl = "one"
 	var firstErr error
 	name, err := mapHRefVar(href, func(v string) string {
+		if isQueryHRefVar(v) {
+			return ""
+		}
 		return varnameRepl
 	})
 	if err != nil {
@@ -323,6 +702,10 @@ func href2name(href string) (string, error) {
 		name = name[1:]
 	}
 	name = strings.Replace(name, "/", ".", -1)
+	if name == "" {
+		// The root of the API, e.g its index.
+		name = "index"
+	}
 	return name, nil
 }
 
@@ -477,6 +860,9 @@ func varsFromHRef(href string) ([]string, error) {
 	var vars []string
 	var firstErr error
 	_, err := mapHRefVar(href, func(v string) string {
+		if isQueryHRefVar(v) {
+			return ""
+		}
 		if v[0] != '{' && v[len(v)-1] != '}' {
 			vars = append(vars, v)
 			return ""
@@ -501,6 +887,38 @@ func varsFromHRef(href string) ([]string, error) {
 	return vars, err
 }
 
+// queryVarsFromHRef returns the variables of the form-style query expansions of href,
+// e.g page and per_page for /spells{?page,per_page}.
+func queryVarsFromHRef(href string) ([]string, error) {
+	var vars []string
+	var firstErr error
+	_, err := mapHRefVar(href, func(v string) string {
+		if !isQueryHRefVar(v) {
+			return ""
+		}
+		for _, qv := range strings.Split(v[2:len(v)-1], ",") {
+			// The explode modifier doesn't change the way we decode the values.
+			qv = strings.TrimSuffix(qv, "*")
+			uv, err := unescapePctEnc(qv)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return ""
+			}
+			vars = append(vars, string(uv))
+		}
+		return ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return vars, nil
+}
+
 // mapHRefVar runs varFunc on each variable in href. It can also serve as a for-each.
 func mapHRefVar(href string, varFunc func(string) string) (string, error) {
 	var (
@@ -542,50 +960,67 @@ func mapHRefVar(href string, varFunc func(string) string) (string, error) {
 }
 
 func capitalize(s string) string {
+	if s == "" {
+		return ""
+	}
 	r, size := utf8.DecodeRuneInString(s)
 	return fmt.Sprintf("%c%s", unicode.ToUpper(r), s[size:])
 }
 
-func symbolName(s string) string {
-	return capitalize(toUpperAfterAny(s, ".-_ "))
-}
-
-func ref2name(ref string) string {
-	// Strip leading #/definitions/
-	name := strings.Replace(ref, "#/definitions/", "", 1)
-	// Hyphenify the remaining ones
-	name = strings.Replace(name, "/definitions/", "-", -1)
-	return symbolName(name)
-}
-
-func toUpperAfterAny(s string, chars string) string {
+// docComment returns text as the lines of a Go comment, or "" if text is blank.
+func docComment(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
 	var buf bytes.Buffer
-	var upnext bool
-OuterLoop:
-	for _, r := range s {
-		for _, c := range chars {
-			if r == c {
-				upnext = true
-				continue OuterLoop
-			}
-		}
-		if upnext {
-			_, _ = buf.WriteRune(unicode.ToUpper(r))
-			upnext = false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if line == "" {
+			_, _ = buf.WriteString("//\n")
 			continue
 		}
-		_, _ = buf.WriteRune(r)
+		fmt.Fprintf(&buf, "// %s\n", line)
 	}
 	return buf.String()
 }
 
+// definitionsPathName returns the name of the schema at the JSON pointer ref in the definitions
+// or $defs of a document, e.g spell-name for #/definitions/spell/$defs/name.
+func definitionsPathName(ref string) string {
+	// Strip leading #/definitions/
+	for _, prefix := range []string{"#/definitions/", "#/$defs/"} {
+		if strings.Contains(ref, prefix) {
+			ref = strings.Replace(ref, prefix, "", 1)
+			break
+		}
+	}
+	// Hyphenify the remaining ones
+	return strings.NewReplacer("/definitions/", "-", "/$defs/", "-").Replace(ref)
+}
+
 // json types
 
 // JSONObject represents the object primitive type of the JSON format.
 type JSONObject struct {
 	Name   string
 	Fields JSONFieldList
-	ref    string
+	// Embedded holds the objects composed with allOf whose Go type is embedded.
+	Embedded []JSONType
+	// AdditionalProperties is the type of the properties not declared in Fields,
+	// if additionalProperties or patternProperties describe them.
+	AdditionalProperties JSONType
+	// AdditionalPropertiesConstraints holds the validation keywords of the AdditionalProperties.
+	AdditionalPropertiesConstraints Constraints
+	// AdditionalPropertiesPattern is the pattern of the keys whose values are checked against
+	// the AdditionalPropertiesConstraints, if they're those of a patternProperties.
+	AdditionalPropertiesPattern string
+	// Description is the description of the object's schema.
+	Description string
+	ref         string
+	// recursive is true if the object stands for the type of ref while it's being parsed,
+	// i.e the type refers to itself.
+	recursive bool
 }
 
 // Type implements Type() of the JSONType interface.
@@ -603,11 +1038,38 @@ func (o JSONObject) TypeName() string {
 	return o.Name
 }
 
+// isEmpty returns true if the object has no properties, even embedded ones.
+func (o JSONObject) isEmpty() bool {
+	return len(o.Fields) == 0 && len(o.Embedded) == 0
+}
+
+// allFields returns the fields of the object, including those of its embedded objects.
+func (o JSONObject) hasField(name string) bool {
+	for _, f := range o.allFields() {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (o JSONObject) allFields() JSONFieldList {
+	fields := make(JSONFieldList, 0, len(o.Fields))
+	for _, e := range o.Embedded {
+		if eo, ok := e.(JSONObject); ok {
+			fields = append(fields, eo.allFields()...)
+		}
+	}
+	return append(fields, o.Fields...)
+}
+
 // JSONArray represents the array primitive type of the JSON format.
 type JSONArray struct {
 	Name  string
 	ref   string
 	Items JSONType
+	// ItemConstraints holds the validation keywords of the items.
+	ItemConstraints Constraints
 }
 
 // Type implements Type() of the JSONType interface.
@@ -625,9 +1087,33 @@ func (a JSONArray) TypeName() string {
 	return a.Name
 }
 
+// JSONMap represents an object of the JSON format whose properties are all of the same type,
+// as described by additionalProperties or patternProperties.
+type JSONMap struct {
+	ref    string
+	Values JSONType
+	// ValueConstraints holds the validation keywords of the values.
+	ValueConstraints Constraints
+	// KeyPattern is the pattern of the keys whose values are checked against the ValueConstraints,
+	// if they're those of a patternProperties.
+	KeyPattern string
+}
+
+// Type implements Type() of the JSONType interface.
+func (m JSONMap) Type() string {
+	return "map"
+}
+
+// Ref implements Ref() of the JSONType interface.
+func (m JSONMap) Ref() string {
+	return m.ref
+}
+
 // JSONString represents the string primitive type of the JSON format.
 type JSONString struct {
 	ref string
+	// Format is the format of the string, if it's one known by dispel.
+	Format string
 }
 
 // Type implements Type() of the JSONType interface.
@@ -658,6 +1144,8 @@ func (b JSONBoolean) Ref() string {
 // JSONInteger represents the integer primitive type of the JSON format.
 type JSONInteger struct {
 	ref string
+	// Format is the format of the integer, if it's one known by dispel.
+	Format string
 }
 
 // Type implements Type() of the JSONType interface.
@@ -673,6 +1161,8 @@ func (i JSONInteger) Ref() string {
 // JSONNumber represents the number primitive type of the JSON format.
 type JSONNumber struct {
 	ref string
+	// Format is the format of the number, if it's one known by dispel.
+	Format string
 }
 
 // Type implements Type() of the JSONType interface.
@@ -700,6 +1190,82 @@ func (n JSONNull) Ref() string {
 	return n.ref
 }
 
+// JSONGoType represents a JSON value whose Go type is supplied by the user,
+// with the GoTypes of the SchemaParser.
+type JSONGoType struct {
+	ref string
+	// GoType is the fully-qualified Go type, e.g github.com/shopspring/decimal.Decimal.
+	GoType string
+}
+
+// Type implements Type() of the JSONType interface.
+func (g JSONGoType) Type() string {
+	return g.GoType
+}
+
+// Ref implements Ref() of the JSONType interface.
+func (g JSONGoType) Ref() string {
+	return g.ref
+}
+
+// ImportPath returns the import path of the package of the Go type, or "" if it's a predeclared type.
+func (g JSONGoType) ImportPath() string {
+	importPath, _ := splitGoType(g.GoType)
+	return importPath
+}
+
+// splitGoType splits the fully-qualified Go type goType in the import path of its package,
+// and the Go type as written in the importing package.
+//
+// The name of the package is assumed to be the last element of its import path,
+// without major version suffix.
+func splitGoType(goType string) (string, string) {
+	prefix := goType[:len(goType)-len(strings.TrimLeft(goType, "*[]"))]
+	qualifiedName := goType[len(prefix):]
+	i := strings.LastIndex(qualifiedName, ".")
+	if i == -1 {
+		return "", goType
+	}
+	importPath := qualifiedName[:i]
+	elems := strings.Split(importPath, "/")
+	pkgName := elems[len(elems)-1]
+	if len(elems) > 1 && majorVersionRx.MatchString(pkgName) {
+		pkgName = elems[len(elems)-2]
+	}
+	if j := strings.Index(pkgName, "."); j != -1 {
+		pkgName = pkgName[:j]
+	}
+	return importPath, prefix + pkgName + qualifiedName[i:]
+}
+
+var majorVersionRx = regexp.MustCompile(`^v[0-9]+$`)
+
+// JSONNullable represents a JSON type which also accepts null, e.g with the "type": ["string", "null"] schema.
+// Its Go type is a pointer, unless the Go type of its Value can already be nil.
+//
+// A JSONNullable has no ref: it's the ref of its Value.
+type JSONNullable struct {
+	Value JSONType
+}
+
+// Type implements Type() of the JSONType interface.
+func (n JSONNullable) Type() string {
+	return n.Value.Type()
+}
+
+// Ref implements Ref() of the JSONType interface.
+func (n JSONNullable) Ref() string {
+	return ""
+}
+
+// nonNullType returns the type of the non-null values of jt.
+func nonNullType(jt JSONType) JSONType {
+	if n, ok := jt.(JSONNullable); ok {
+		return n.Value
+	}
+	return jt
+}
+
 // JSONDateTime represents the string primitive type of the JSON format.
 // Its underlying time is a RFC3339 date time.
 type JSONDateTime struct {
@@ -716,49 +1282,265 @@ func (dt JSONDateTime) Ref() string {
 	return dt.ref
 }
 
-// JSONField represents one property of a JSONObject.
-type JSONField struct {
-	Name string
-	Type JSONType
+// JSONEnum represents a string of the JSON format restricted to a set of values.
+type JSONEnum struct {
+	Name   string
+	ref    string
+	Values []string
+	// Description is the description of the enum's schema.
+	Description string
 }
 
-// JSONFieldList implements alphabetical sorting of a JSONObject property list.
-type JSONFieldList []JSONField
+// Type implements Type() of the JSONType interface.
+func (e JSONEnum) Type() string {
+	return "enum"
+}
 
-func (fl JSONFieldList) Len() int           { return len(fl) }
-func (fl JSONFieldList) Swap(i, j int)      { fl[i], fl[j] = fl[j], fl[i] }
-func (fl JSONFieldList) Less(i, j int) bool { return fl[i].Name < fl[j].Name }
+// Ref implements Ref() of the JSONType interface.
+func (e JSONEnum) Ref() string {
+	return e.ref
+}
 
-// JSONType is the interface implemented by objects that represents a JSON type defined in a JSON Schema.
-type JSONType interface {
-	// Type returns a string representation of this type.
-	Type() string
-	// Ref returns the absolute reference of type as found in the JSON Schema which defined it.
-	Ref() string
+// TypeName implements the TypeNamer interface.
+func (e JSONEnum) TypeName() string {
+	return e.Name
 }
 
-// ResolveType resolves the JSONType by following its Ref backwards.
-func (sp *SchemaParser) ResolveType(jt JSONType) JSONType {
-	ref := jt.Ref()
-	if ref != "" {
-		tjt, ok := sp.refJSONTypeMap[ref]
-		if !ok {
-			log.Panicf("unregistered json type %s", ref)
+// ConstName returns the name of the Go constant generated for the enum value v.
+func (e JSONEnum) ConstName(v string) string {
+	return e.Name + enumValueName(v)
+}
+
+// enumValueName returns an identifier suffix for the enum value v.
+// Characters which can't appear in a Go identifier act as word separators.
+func enumValueName(v string) string {
+	var buf bytes.Buffer
+	upnext := true
+	for _, r := range v {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upnext = true
+			continue
 		}
-		return tjt
+		if upnext {
+			r = unicode.ToUpper(r)
+			upnext = false
+		}
+		_, _ = buf.WriteRune(r)
 	}
-	return jt
+	if buf.Len() == 0 {
+		return "Empty"
+	}
+	return buf.String()
 }
 
-// JSONToGoType prints Go source code for the JSONType.
-// globalScope tells whether we are in the package scope of the go source file,
-// or inside a type.
-func (sp *SchemaParser) JSONToGoType(jt JSONType, globalScope bool) string {
-	jt = sp.ResolveType(jt)
-	// if we're not in the package scope, we'll just write the name of
-	// the type, if it has one.
-	n, ok := jt.(TypeNamer)
-	if ok && !globalScope {
+// JSONUnion represents a value matching one of several JSON types,
+// as declared by the oneOf and anyOf keywords.
+type JSONUnion struct {
+	Name     string
+	ref      string
+	Variants []JSONType
+	// OneOf tells whether exactly one variant may match (oneOf), or at least one (anyOf).
+	OneOf bool
+	// Discriminator is the property telling the variants apart, if any.
+	Discriminator string
+	// DiscriminatorValues holds the value of the Discriminator property for each variant.
+	DiscriminatorValues []string
+	// Description is the description of the union's schema.
+	Description string
+}
+
+// Type implements Type() of the JSONType interface.
+func (u JSONUnion) Type() string {
+	return "union"
+}
+
+// Ref implements Ref() of the JSONType interface.
+func (u JSONUnion) Ref() string {
+	return u.ref
+}
+
+// TypeName implements the TypeNamer interface.
+func (u JSONUnion) TypeName() string {
+	return u.Name
+}
+
+// JSONField represents one property of a JSONObject.
+type JSONField struct {
+	Name string
+	Type JSONType
+	// Required tells whether the property is listed in the "required" keyword of its object.
+	Required bool
+	// Constraints holds the validation keywords of the property.
+	Constraints Constraints
+	// Description is the description of the property, or of the schema it references.
+	Description string
+	// ReadOnly tells whether the property is set by the server only.
+	// It's omitted from the input variant of its object.
+	ReadOnly bool
+	// Default is the default value of the property, as decoded from the JSON Schema.
+	// It's nil if the property has none, or if it's not supported for its type.
+	Default interface{}
+	// GoName is the name of the struct field of the property, if set by x-go-name.
+	GoName string
+}
+
+// Constraints represents the validation keywords of a JSON Schema which are enforced
+// by the Validate() methods of the generated types.
+type Constraints struct {
+	MultipleOf       float64
+	Maximum          *float64
+	ExclusiveMaximum bool
+	Minimum          *float64
+	ExclusiveMinimum bool
+	MinLength        int
+	MaxLength        int
+	Pattern          string
+	MinItems         int
+	MaxItems         int
+	UniqueItems      bool
+	// Format is the format of a string, if it's one of the formats checked by Validate().
+	Format string
+}
+
+// IsZero returns true if c holds no constraint.
+func (c Constraints) IsZero() bool {
+	return c == Constraints{}
+}
+
+// constraintsFromSchema returns the Constraints declared by the schema, of the draft d.
+// An error is returned if the schema's pattern is not a valid regular expression,
+// or if its exclusiveMaximum or exclusiveMinimum don't have the type of the draft.
+func (sp *SchemaParser) constraintsFromSchema(schema *Schema, d Draft) (Constraints, error) {
+	if schema.Pattern != "" {
+		if _, err := regexp.Compile(schema.Pattern); err != nil {
+			return Constraints{}, sp.schemaError(schema, "/pattern", fmt.Sprintf("invalid pattern %q: %v", schema.Pattern, err))
+		}
+	}
+	maximum, exclusiveMaximum, minimum, exclusiveMinimum, err := sp.boundsFromSchema(schema, d)
+	if err != nil {
+		return Constraints{}, err
+	}
+	var format string
+	if schema.Type.String() == "string" && checkedFormats[schema.Format] {
+		format = schema.Format
+	}
+	return Constraints{
+		MultipleOf:       schema.MultipleOf,
+		Maximum:          maximum,
+		ExclusiveMaximum: exclusiveMaximum,
+		Minimum:          minimum,
+		ExclusiveMinimum: exclusiveMinimum,
+		MinLength:        schema.MinLength,
+		MaxLength:        schema.MaxLength,
+		Pattern:          schema.Pattern,
+		MinItems:         schema.MinItems,
+		MaxItems:         schema.MaxItems,
+		UniqueItems:      schema.UniqueItems,
+		Format:           format,
+	}, nil
+}
+
+// typeConstraints returns the Constraints declared by the schema of a value of type typ.
+// The values of the user's Go types can't be checked: they have none.
+func (sp *SchemaParser) typeConstraints(typ JSONType, schema *Schema) (Constraints, error) {
+	// The constraints of a oneOf or anyOf of a type and null are those of the type.
+	variantSchemas := schema.OneOf
+	if len(variantSchemas) == 0 {
+		variantSchemas = schema.AnyOf
+	}
+	if _, ok := typ.(JSONNullable); ok && len(variantSchemas) > 1 {
+		nonNullSchemas, err := sp.nonNullVariants(variantSchemas)
+		if err != nil {
+			return Constraints{}, err
+		}
+		if len(nonNullSchemas) == 1 {
+			if schema, err = sp.ResolveSchema(nonNullSchemas[0]); err != nil {
+				return Constraints{}, err
+			}
+		}
+	}
+	constraints, err := sp.constraintsFromSchema(schema, sp.draft(schema))
+	if err != nil {
+		return Constraints{}, err
+	}
+	if _, ok := nonNullType(typ).(JSONGoType); ok {
+		return Constraints{}, nil
+	}
+	return constraints, nil
+}
+
+// formatGoTypes maps the formats of the JSON primitive types to the Go types of their values,
+// when they differ from the default Go type of the JSON type.
+var formatGoTypes = map[string]map[string]string{
+	"integer": {"int32": "int32", "int64": "int64"},
+	"number":  {"float": "float32", "double": "float64"},
+	"string":  {"byte": "[]byte"},
+}
+
+// checkedFormats are the formats of strings checked by the Validate() methods.
+var checkedFormats = map[string]bool{
+	"date":     true,
+	"time":     true,
+	"duration": true,
+	"uri":      true,
+	"email":    true,
+	"uuid":     true,
+}
+
+// knownFormat returns the format of the schema, if it's one known by dispel for its type.
+func knownFormat(schema *Schema) string {
+	t := schema.Type.String()
+	if _, ok := formatGoTypes[t][schema.Format]; ok {
+		return schema.Format
+	}
+	if t == "string" && checkedFormats[schema.Format] {
+		return schema.Format
+	}
+	return ""
+}
+
+// JSONFieldList holds the fields of a JSON object.
+// It implements alphabetical sorting of a JSONObject property list.
+type JSONFieldList []JSONField
+
+func (fl JSONFieldList) Len() int           { return len(fl) }
+func (fl JSONFieldList) Swap(i, j int)      { fl[i], fl[j] = fl[j], fl[i] }
+func (fl JSONFieldList) Less(i, j int) bool { return fl[i].Name < fl[j].Name }
+
+// JSONType is the interface implemented by objects that represents a JSON type defined in a JSON Schema.
+type JSONType interface {
+	// Type returns a string representation of this type.
+	Type() string
+	// Ref returns the absolute reference of type as found in the JSON Schema which defined it.
+	Ref() string
+}
+
+// ResolveType resolves the JSONType by following its Ref backwards.
+func (sp *SchemaParser) ResolveType(jt JSONType) JSONType {
+	ref := jt.Ref()
+	if ref != "" {
+		tjt, ok := sp.refJSONTypeMap[ref]
+		if o, isObject := jt.(JSONObject); !ok && isObject && o.recursive {
+			// The type is still being parsed.
+			return jt
+		}
+		if !ok {
+			log.Panicf("unregistered json type %s", ref)
+		}
+		return tjt
+	}
+	return jt
+}
+
+// JSONToGoType prints Go source code for the JSONType.
+// globalScope tells whether we are in the package scope of the go source file,
+// or inside a type.
+func (sp *SchemaParser) JSONToGoType(jt JSONType, globalScope bool) string {
+	jt = sp.ResolveType(jt)
+	// if we're not in the package scope, we'll just write the name of
+	// the type, if it has one.
+	n, ok := jt.(TypeNamer)
+	if ok && !globalScope {
 		if n.TypeName() == "" {
 			log.Panicf("no unnamed type should exist")
 		}
@@ -769,80 +1551,241 @@ func (sp *SchemaParser) JSONToGoType(jt JSONType, globalScope bool) string {
 			return fmt.Sprintf("[]%s", sp.JSONToGoType(t.Items, false))
 		case JSONObject:
 			// we don't want types aliased to an interface{}
-			if len(t.Fields) == 0 {
+			if t.isEmpty() {
 				return "interface{}"
 			}
 		}
-		return symbolName(n.TypeName())
+		return n.TypeName()
 	}
 	switch j := jt.(type) {
 	case JSONString:
+		if goType, ok := formatGoTypes["string"][j.Format]; ok {
+			return goType
+		}
+		return "string"
+	case JSONEnum:
 		return "string"
 	case JSONDateTime:
 		return "time.Time"
 	case JSONBoolean:
 		return "bool"
 	case JSONInteger:
+		if goType, ok := formatGoTypes["integer"][j.Format]; ok {
+			return goType
+		}
 		return "int"
 	case JSONNumber:
+		if goType, ok := formatGoTypes["number"][j.Format]; ok {
+			return goType
+		}
 		return "float64"
+	case JSONGoType:
+		_, goType := splitGoType(j.GoType)
+		return goType
 	case JSONObject:
 		// if type has no fields, return an interface{}
-		if len(j.Fields) == 0 {
+		if j.isEmpty() {
 			return "interface{}"
 		}
 		var buf bytes.Buffer
 		_, _ = buf.WriteString("struct {\n")
+		for _, e := range j.Embedded {
+			fmt.Fprintf(&buf, "%s\n", sp.JSONToGoType(e, false))
+		}
 		for _, f := range j.Fields {
-			// we don't want a type with a slice as underlying type.
-			// See https://golang.org/ref/spec#Assignability
-			a, ok := f.Type.(JSONArray)
-			var fieldTypeName string
-			if ok {
-				fieldTypeName = fmt.Sprintf("[]%s", sp.JSONToGoType(a.Items, false))
-			} else {
-				fieldTypeName = sp.JSONToGoType(f.Type, false)
-			}
-			fmt.Fprintf(&buf, "%s %s `json:\"%s\"`\n", symbolName(f.Name), fieldTypeName, f.Name)
+			fmt.Fprintf(&buf, "%s%s\n", docComment(f.Description), sp.JSONFieldToGoField(f))
+		}
+		if j.AdditionalProperties != nil {
+			fmt.Fprintf(&buf, "AdditionalProperties map[string]%s `json:\"-\"`\n", sp.JSONToGoType(j.AdditionalProperties, false))
 		}
 		_, _ = buf.WriteString("}")
 		return buf.String()
 	case JSONArray:
 		return fmt.Sprintf("[]%s", sp.JSONToGoType(j.Items, false))
+	case JSONMap:
+		return fmt.Sprintf("map[string]%s", sp.JSONToGoType(j.Values, false))
+	case JSONNullable:
+		goType := sp.JSONToGoType(j.Value, false)
+		if isNilableGoType(goType) {
+			return goType
+		}
+		return "*" + goType
+	case JSONUnion:
+		var buf bytes.Buffer
+		_, _ = buf.WriteString("struct {\n")
+		for _, variant := range j.Variants {
+			fieldTypeName, isPtr := sp.unionVariantGoType(variant)
+			if isPtr {
+				fieldTypeName = "*" + fieldTypeName
+			}
+			fmt.Fprintf(&buf, "%s %s\n", sp.unionVariantName(variant), fieldTypeName)
+		}
+		_, _ = buf.WriteString("}")
+		return buf.String()
 	default:
 		log.Panicf("unhandled jsonType %#v", j)
 		return ""
 	}
 }
 
+// JSONFieldToGoField prints Go source code for the struct field of the JSONField.
+//
+// Required fields are value fields. Optional fields are tagged with omitempty and,
+// unless their Go type can already be nil, they are pointers; this lets the user tell
+// an absent property from its zero value.
+func (sp *SchemaParser) JSONFieldToGoField(f JSONField) string {
+	fieldTypeName, isPtr := sp.jsonFieldGoType(f)
+	if isPtr {
+		fieldTypeName = "*" + fieldTypeName
+	}
+	tag := f.Name
+	if !f.Required {
+		tag += ",omitempty"
+	}
+	return fmt.Sprintf("%s %s `json:\"%s\"`", sp.goFieldName(f), fieldTypeName, tag)
+}
+
+// jsonFieldGoType returns the Go type of the JSONField's non-null value, and whether
+// the struct field holds a pointer to it.
+func (sp *SchemaParser) jsonFieldGoType(f JSONField) (string, bool) {
+	typ := nonNullType(f.Type)
+	_, nullable := f.Type.(JSONNullable)
+	// we don't want a type with a slice as underlying type.
+	// See https://golang.org/ref/spec#Assignability
+	var fieldTypeName string
+	if a, ok := typ.(JSONArray); ok {
+		fieldTypeName = fmt.Sprintf("[]%s", sp.JSONToGoType(a.Items, false))
+	} else {
+		fieldTypeName = sp.JSONToGoType(typ, false)
+	}
+	// A struct can't hold a value of its own type.
+	o, ok := typ.(JSONObject)
+	recursive := ok && o.recursive
+	return fieldTypeName, (!f.Required || nullable || recursive) && !isNilableGoType(fieldTypeName)
+}
+
+// unionVariantGoType returns the Go type of the variant of a JSONUnion, and whether
+// the union's struct field holds a pointer to it.
+func (sp *SchemaParser) unionVariantGoType(variant JSONType) (string, bool) {
+	return sp.jsonFieldGoType(JSONField{Type: variant})
+}
+
+// isNilableGoType returns true if the zero value of the Go type printed as goType is nil.
+func isNilableGoType(goType string) bool {
+	return strings.HasPrefix(goType, "[]") ||
+		strings.HasPrefix(goType, "map[") ||
+		strings.HasPrefix(goType, "*") ||
+		goType == "interface{}"
+}
+
 // InvalidSchemaRefError represents an error which happens when an invalid $ref is found in a JSON Schema.
 // Typically, it's a $ref which is unsupported or can't be dereferenced.
 type InvalidSchemaRefError struct {
 	Ref string
 	Msg string
+	// Pointer is the JSON pointer of the node holding the $ref, if known.
+	// It's prefixed with the URI of its document if it's not the root schema's document,
+	// e.g #/definitions/server/properties/owner/$ref or common.json#/definitions/error/$ref.
+	Pointer string
+	// Location is the position of the node holding the $ref in its source document, if known.
+	Location Location
 }
 
 func (e InvalidSchemaRefError) Error() string {
-	return fmt.Sprintf("invalid $ref %q: %s", string(e.Ref), e.Msg)
+	return locatedMsg(e.Pointer, e.Location, fmt.Sprintf("invalid $ref %q: %s", string(e.Ref), e.Msg))
 }
 
 // InvalidSchemaError represents an error which happens when parsing a (sub)schema fails.
 type InvalidSchemaError struct {
-	Schema Schema
-	Msg    string
+	// Pointer is the JSON pointer of the offending node, if known.
+	// It's prefixed with the URI of its document if it's not the root schema's document,
+	// e.g #/definitions/server/links/3/href or common.json#/definitions/error.
+	Pointer string
+	// Location is the position of the offending node in its source document, if known.
+	Location Location
+	Msg      string
 }
 
 func (e InvalidSchemaError) Error() string {
-	return string(e.Msg)
+	return locatedMsg(e.Pointer, e.Location, e.Msg)
+}
+
+// locatedMsg prefixes msg with the location and the JSON pointer it's about, when they're known,
+// e.g api.json:12:5: #/definitions/server/links/3/href: msg.
+func locatedMsg(pointer string, loc Location, msg string) string {
+	if pointer != "" {
+		msg = pointer + ": " + msg
+	}
+	if loc != (Location{}) {
+		msg = loc.String() + ": " + msg
+	}
+	return msg
+}
+
+// schemaError returns an InvalidSchemaError with the message msg about the node of schema
+// at the relative JSON pointer rel, e.g /links/3/href, or about schema itself if rel is empty.
+func (sp *SchemaParser) schemaError(schema *Schema, rel string, msg string) InvalidSchemaError {
+	pointer, loc := sp.locate(schema, rel)
+	return InvalidSchemaError{Pointer: pointer, Location: loc, Msg: msg}
+}
+
+// locateError returns err located at the node of schema at the relative JSON pointer rel.
+//
+// The errors of the parser which are already located, and the *TypeRedefinitionError, are returned as is;
+// the other errors become an InvalidSchemaError.
+func (sp *SchemaParser) locateError(err error, schema *Schema, rel string) error {
+	switch e := err.(type) {
+	case InvalidSchemaError:
+		if e.Pointer != "" {
+			return err
+		}
+		return sp.schemaError(schema, rel, e.Msg)
+	case InvalidSchemaRefError:
+		if e.Pointer != "" {
+			return err
+		}
+		e.Pointer, e.Location = sp.locate(schema, rel)
+		return e
+	case *TypeRedefinitionError:
+		return err
+	}
+	return sp.schemaError(schema, rel, err.Error())
 }
 
 // SchemaParser provides a parser for a Schema instance.
 //
 // It allows to parse its routes and data structures.
 type SchemaParser struct {
-	RootSchema     *Schema
-	Log            *log.Logger
+	RootSchema *Schema
+	Log        *log.Logger
+	// RefLoader loads the documents referenced by $refs which are not in the RootSchema's document.
+	RefLoader RefLoader
+	// GoTypes maps formats and $refs, e.g uuid or #/definitions/money, to the fully-qualified Go types
+	// of their values, e.g github.com/shopspring/decimal.Decimal.
+	// They override the Go types dispel generates for them; a $ref has precedence over a format.
+	GoTypes map[string]string
+	// SortFields sorts the fields of the objects by name.
+	// By default, they're in the order of the properties in the schema document.
+	SortFields bool
+	// CollectErrors makes ParseRoutes report all the errors it finds, instead of the first one:
+	// the invalid $refs of the loaded documents, the duplicate rels, the errors of each link
+	// and all the type redefinitions.
+	CollectErrors bool
+	// Namer names the Go identifiers of the generated code. If nil, DefaultNamer is used.
+	Namer          Namer
 	refJSONTypeMap map[string]JSONType
+	// parsing holds the schemas whose type is being parsed, and whether it refers to itself.
+	parsing map[*Schema]bool
+	// documents holds the loaded schema documents, by URI.
+	documents map[string]*Schema
+	// baseURIs holds the base URI of each schema of the loaded documents.
+	baseURIs map[*Schema]string
+	// schemaIDs holds the schemas of the loaded documents which have an id, by resolved id.
+	schemaIDs map[string]*Schema
+	// drafts holds the draft of each schema of the loaded documents.
+	drafts map[*Schema]Draft
+	// nodes locates each schema of the loaded documents.
+	nodes map[*Schema]schemaNode
 }
 
 func (sp *SchemaParser) logf(format string, v ...interface{}) {
@@ -853,75 +1796,71 @@ func (sp *SchemaParser) logf(format string, v ...interface{}) {
 
 // ParseRoutes parses the Schema and returns a list of Route instances.
 //
+// The routes are the links of the root schema, of its properties and of its definitions.
+// A definition referenced by several properties has its routes parsed once.
+//
 // Various errors may be returned, among them InvalidSchemaError and *TypeRedefinitionError.
+// If CollectErrors is set, the parsing goes on after an error, and all of them are returned as SchemaErrors,
+// along with the routes which could be parsed.
 func (sp *SchemaParser) ParseRoutes() (Routes, error) {
 	if sp.RootSchema == nil {
-		return nil, InvalidSchemaError{Schema{}, "no schema provided"}
+		return nil, InvalidSchemaError{Msg: "no schema provided"}
 	}
-	if sp.RootSchema.Type != "object" {
-		return nil, InvalidSchemaError{*sp.RootSchema, "root schema is not an object"}
+	if sp.RootSchema.Type.String() != "object" {
+		return nil, sp.schemaError(sp.RootSchema, "/type", "root schema is not an object")
 	}
 
-	var schemaRoutes Routes
-	for propertyName, property := range sp.RootSchema.Properties {
-		resProperty, err := sp.ResolveSchema(property)
+	var (
+		schemaRoutes Routes
+		errs         SchemaErrors
+	)
+	// collect records err if the errors are collected; it returns false if the parsing must stop.
+	collect := func(err error) bool {
+		if !sp.CollectErrors {
+			return false
+		}
+		errs = errs.add(err)
+		return true
+	}
+	if sp.CollectErrors {
+		for _, err := range sp.invalidRefErrors() {
+			errs = errs.add(err)
+		}
+	}
+	// A schema referenced several times is the same resource.
+	parsed := make(map[*Schema]bool)
+	for _, res := range sp.routeResources() {
+		propertyName := res.name
+		resProperty, err := sp.ResolveSchema(res.schema)
 		if err != nil {
+			if collect(err) {
+				continue
+			}
 			return nil, err
 		}
+		if parsed[resProperty] {
+			continue
+		}
+		parsed[resProperty] = true
 		linksRelAttr := make(map[string]bool)
-		for _, link := range resProperty.Links {
+		for i, link := range resProperty.Links {
 			link.ApplyDefaults()
 
 			if exists := linksRelAttr[link.Rel]; exists {
-				return nil, InvalidSchemaError{*property, fmt.Sprintf("duplicate link \"rel\" %s", link.Rel)}
-			}
-			linksRelAttr[link.Rel] = true
-
-			p, err := href2path(link.HRef)
-			if err != nil {
-				return nil, err
-			}
-			n, err := href2name(link.HRef)
-			if err != nil {
+				err := sp.schemaError(resProperty, fmt.Sprintf("/links/%d/rel", i), fmt.Sprintf("duplicate link \"rel\" %s", link.Rel))
+				if collect(err) {
+					continue
+				}
 				return nil, err
 			}
-			route := &Route{
-				Path:   p,
-				Name:   n,
-				Method: strings.ToUpper(link.Method),
-				Link:   link,
-			}
-			sp.logf("discovered route %s -> %s %q ", route.Name, route.Method, route.Path)
-
-			route.InputIsNotJSON = !link.ReceivesJSON()
-			route.OutputIsNotJSON = !link.SendsJSON()
+			linksRelAttr[link.Rel] = true
 
-			rp, err := sp.RouteParamsFromLink(&link, resProperty)
+			route, err := sp.parseRoute(propertyName, resProperty, i, link)
 			if err != nil {
-				return nil, err
-			}
-			if rp == nil {
-				rp = make([]RouteParam, 0)
-			}
-			route.RouteParams = rp
-
-			// Ignore link input if it's not receiving application/json
-			if link.Schema != nil && link.ReceivesJSON() {
-				inType, err := sp.JSONTypeFromSchema(fmt.Sprintf("%s%sIn", symbolName(link.Rel), symbolName(propertyName)), link.Schema, link.Schema.Ref)
-				if err != nil {
-					return nil, err
-				}
-				route.InType = inType
-				sp.logf(" --> found input type %s", inType.Type())
-			}
-			// Ignore link output if it's not sending application/json
-			if link.TargetSchema != nil && link.SendsJSON() {
-				outType, err := sp.JSONTypeFromSchema(fmt.Sprintf("%s%sOut", symbolName(link.Rel), symbolName(propertyName)), link.TargetSchema, link.TargetSchema.Ref)
-				if err != nil {
-					return nil, err
+				if collect(err) {
+					continue
 				}
-				route.OutType = outType
-				sp.logf(" --> found output type %s", outType.Type())
+				return nil, err
 			}
 			schemaRoutes = append(schemaRoutes, *route)
 		}
@@ -929,84 +1868,370 @@ func (sp *SchemaParser) ParseRoutes() (Routes, error) {
 	sort.Sort(schemaRoutes)
 	typeRedefs, ok := sp.checkNamedTypeRedefinitions(schemaRoutes)
 	if !ok {
-		for name, types := range typeRedefs {
-			// Pick the first we find, for now; we don't want to flood errors
-			return schemaRoutes, &TypeRedefinitionError{
+		names := make([]string, 0, len(typeRedefs))
+		for name := range typeRedefs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			types := typeRedefs[name]
+			err := &TypeRedefinitionError{
 				Name:   name,
 				First:  types[0],
 				Redefs: types[1:],
 			}
+			// Without collecting the errors, report the first by name; we don't want to flood errors
+			if !collect(err) {
+				return schemaRoutes, err
+			}
 		}
 	}
+	if len(errs) > 0 {
+		sort.Sort(errs)
+		return schemaRoutes, errs
+	}
 	return schemaRoutes, nil
 }
 
+// routeResource is a schema whose links are routes, with the name of the types of its routes.
+type routeResource struct {
+	name   string
+	schema *Schema
+}
+
+// routeResources returns the schemas whose links are routes, in this order:
+// the root schema itself, whose links are the API index, its properties, and its definitions,
+// nested ones included. They're named after their property or definition; the root schema has no name.
+func (sp *SchemaParser) routeResources() []routeResource {
+	resources := []routeResource{{"", sp.RootSchema}}
+	for _, propertyName := range sp.RootSchema.PropertyNames() {
+		resources = append(resources, routeResource{propertyName, sp.RootSchema.Properties[propertyName]})
+	}
+	var addDefinitions func(s *Schema)
+	addDefinitions = func(s *Schema) {
+		for _, name := range s.DefinitionNames() {
+			resources = append(resources, routeResource{name, s.Definitions[name]})
+			addDefinitions(s.Definitions[name])
+		}
+		for _, name := range s.DefsNames() {
+			resources = append(resources, routeResource{name, s.Defs[name]})
+			addDefinitions(s.Defs[name])
+		}
+	}
+	addDefinitions(sp.RootSchema)
+	return resources
+}
+
+// parseRoute returns the route of the link i of the resource resProperty, named propertyName.
+func (sp *SchemaParser) parseRoute(propertyName string, resProperty *Schema, i int, link Link) (*Route, error) {
+	linkPointer := fmt.Sprintf("/links/%d", i)
+	p, err := href2path(link.HRef)
+	if err != nil {
+		return nil, sp.locateError(err, resProperty, linkPointer+"/href")
+	}
+	n, err := sp.namer().RouteName(link.HRef)
+	if err != nil {
+		return nil, sp.locateError(err, resProperty, linkPointer+"/href")
+	}
+	route := &Route{
+		Path:   p,
+		Name:   n,
+		Method: strings.ToUpper(link.Method),
+		Link:   link,
+	}
+	sp.logf("discovered route %s -> %s %q ", route.Name, route.Method, route.Path)
+
+	route.InputIsNotJSON = !link.ReceivesJSON()
+	route.OutputIsNotJSON = !link.SendsJSON()
+
+	rp, err := sp.RouteParamsFromLink(&link, resProperty)
+	if err != nil {
+		return nil, sp.locateError(err, resProperty, linkPointer+"/href")
+	}
+	if rp == nil {
+		rp = make([]RouteParam, 0)
+	}
+	route.RouteParams = rp
+
+	resource := propertyName
+	if resource == "" && n != link.Rel {
+		// The links of the root schema have no resource: their types are named after their route,
+		// e.g SelfStatusOut for the self link of /status, unless it's their rel, e.g IndexOut.
+		resource = n
+	}
+	queryType, err := sp.QueryTypeFromLink(sp.namer().LinkTypeName(link.Rel, resource, "Query"), &link, resProperty)
+	if err != nil {
+		return nil, sp.locateError(err, resProperty, linkPointer+"/href")
+	}
+	if queryType != nil {
+		route.QueryType = queryType
+		sp.logf(" --> found query type %s", queryType.Type())
+	}
+
+	// Ignore link input if it's not receiving application/json
+	if link.Schema != nil && link.ReceivesJSON() && !link.SchemaDescribesQuery() {
+		inType, err := sp.JSONTypeFromSchema(sp.namer().LinkTypeName(link.Rel, resource, "In"), link.Schema, sp.refOf(link.Schema))
+		if err != nil {
+			return nil, sp.locateError(err, resProperty, linkPointer+"/schema")
+		}
+		route.InType = sp.inputType(inType)
+		sp.logf(" --> found input type %s", route.InType.Type())
+	}
+	// Ignore link output if it's not sending application/json
+	if link.TargetSchema != nil && link.SendsJSON() {
+		outType, err := sp.JSONTypeFromSchema(sp.namer().LinkTypeName(link.Rel, resource, "Out"), link.TargetSchema, sp.refOf(link.TargetSchema))
+		if err != nil {
+			return nil, sp.locateError(err, resProperty, linkPointer+"/targetSchema")
+		}
+		route.OutType = outType
+		sp.logf(" --> found output type %s", outType.Type())
+	}
+	return route, nil
+}
+
+// inputType returns the variant of typ received in request bodies, see inputVariant.
+// The inline schema of a link is received only: its variant keeps its name.
+func (sp *SchemaParser) inputType(typ JSONType) JSONType {
+	input, changed := sp.inputVariant(typ, make(map[string]bool))
+	if !changed {
+		return input
+	}
+	switch jt := typ.(type) {
+	case JSONObject:
+		if jt.ref == "" {
+			io := input.(JSONObject)
+			io.Name = jt.Name
+			return io
+		}
+	case JSONUnion:
+		if jt.ref == "" {
+			iu := input.(JSONUnion)
+			iu.Name = jt.Name
+			return iu
+		}
+	}
+	return input
+}
+
+// inputVariant returns the variant of typ without readOnly properties, and whether it differs from typ.
+//
+// The variant of an object has no readOnly properties, and has the variants of its embedded objects,
+// of the types of its fields and of its additional properties; the embedded objects left without properties
+// are removed. It's named after the object, with the Input suffix.
+// The variants of arrays, maps and nullable types have the variants of their items or values.
+// The variant of a union has the variants of its variants, and is named with the Input suffix too.
+// The objects referring to themselves are their own variant.
+//
+// visiting holds the names of the objects whose variant is being made.
+func (sp *SchemaParser) inputVariant(typ JSONType, visiting map[string]bool) (JSONType, bool) {
+	switch jt := typ.(type) {
+	case JSONObject:
+		if jt.recursive || visiting[jt.Name] {
+			return typ, false
+		}
+		visiting[jt.Name] = true
+		defer delete(visiting, jt.Name)
+
+		var changed bool
+		input := JSONObject{
+			Name:                            sp.namer().InnerTypeName(jt.Name, "Input", 0),
+			AdditionalPropertiesConstraints: jt.AdditionalPropertiesConstraints,
+			AdditionalPropertiesPattern:     jt.AdditionalPropertiesPattern,
+			Description:                     jt.Description,
+		}
+		for _, e := range jt.Embedded {
+			ie, c := sp.inputVariant(e, visiting)
+			changed = changed || c
+			if c && ie.(JSONObject).isEmpty() {
+				continue
+			}
+			input.Embedded = append(input.Embedded, ie)
+		}
+		for _, f := range jt.Fields {
+			if f.ReadOnly {
+				changed = true
+				continue
+			}
+			var c bool
+			f.Type, c = sp.inputVariant(f.Type, visiting)
+			changed = changed || c
+			input.Fields = append(input.Fields, f)
+		}
+		if jt.AdditionalProperties != nil {
+			var c bool
+			input.AdditionalProperties, c = sp.inputVariant(jt.AdditionalProperties, visiting)
+			changed = changed || c
+		}
+		if !changed {
+			return typ, false
+		}
+		return input, true
+	case JSONUnion:
+		var changed bool
+		variants := make([]JSONType, len(jt.Variants))
+		for i, v := range jt.Variants {
+			var c bool
+			variants[i], c = sp.inputVariant(v, visiting)
+			changed = changed || c
+		}
+		if !changed {
+			return typ, false
+		}
+		jt.Name, jt.ref, jt.Variants = sp.namer().InnerTypeName(jt.Name, "Input", 0), "", variants
+		return jt, true
+	case JSONArray:
+		items, changed := sp.inputVariant(jt.Items, visiting)
+		if !changed {
+			return typ, false
+		}
+		// The variant is no longer the type of the ref.
+		jt.Items, jt.ref = items, ""
+		return jt, true
+	case JSONMap:
+		values, changed := sp.inputVariant(jt.Values, visiting)
+		if !changed {
+			return typ, false
+		}
+		jt.Values, jt.ref = values, ""
+		return jt, true
+	case JSONNullable:
+		value, changed := sp.inputVariant(jt.Value, visiting)
+		return JSONNullable{Value: value}, changed
+	}
+	return typ, false
+}
+
 // ResolveSchema takes a schema and recursively follows its $ref, if any.
-// An error is returned if it fails to resolve a ref along the way.
+// An error is returned if it fails to resolve a ref along the way, or if the refs form a cycle.
 func (sp *SchemaParser) ResolveSchema(schema *Schema) (*Schema, error) {
-	var (
-		s   *Schema
-		err error
-	)
-	for s = schema; s.Ref != ""; {
-		s, err = sp.ResolveSchemaRef(s.Ref, s)
-		if err != nil {
+	var chain []*Schema
+	s := schema
+	for s.Ref != "" {
+		for i := range chain {
+			if chain[i] == s {
+				return nil, sp.refCycleError(chain[i:])
+			}
+		}
+		chain = append(chain, s)
+		var err error
+		if s, err = sp.ResolveSchemaRef(s.Ref, s); err != nil {
 			return nil, err
 		}
 	}
 	return s, nil
 }
 
+// refCycleError returns the error of the schemas of cycle, whose $ref each points to the next one,
+// and the last one to the first one.
+//
+// The cycle is reported from the schema with the smallest ref, so that it's the same error
+// whichever schema of the cycle it's found from, e.g
+// $ref cycle #/definitions/a -> #/definitions/b -> #/definitions/a.
+func (sp *SchemaParser) refCycleError(cycle []*Schema) error {
+	// The ref of each schema is the one of the schema pointing to it.
+	refs := make([]string, len(cycle))
+	for i, s := range cycle {
+		refs[(i+1)%len(cycle)] = sp.refOf(s)
+	}
+	first := 0
+	for i, ref := range refs {
+		if ref < refs[first] {
+			first = i
+		}
+	}
+	path := append(append([]string{}, refs[first:]...), refs[:first+1]...)
+	s := cycle[first]
+	err := InvalidSchemaRefError{Ref: s.Ref, Msg: "$ref cycle " + strings.Join(path, " -> ")}
+	return sp.locateError(err, s, "/$ref")
+}
+
 // TypeNamer defines methods for types which are named types.
 type TypeNamer interface {
 	TypeName() string
 }
 
+// schemaKeywordFields maps the keywords of a Schema to the names of their fields, e.g $defs to Defs.
+var schemaKeywordFields = func() map[string]string {
+	m := make(map[string]string)
+	st := reflect.TypeOf(Schema{})
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if keyword := strings.Split(f.Tag.Get("json"), ",")[0]; keyword != "" && keyword != "-" {
+			m[keyword] = f.Name
+		}
+	}
+	return m
+}()
+
+// schemaKeywordField returns the name of the field of a Schema holding the keyword.
+func schemaKeywordField(keyword string) string {
+	if name, ok := schemaKeywordFields[keyword]; ok {
+		return name
+	}
+	return capitalize(keyword)
+}
+
 // ResolveSchemaRef takes a $ref string and returns the pointed schema.
 //
 // The ref, if relative, is resolved against the relSchema schema. The ref is dereferenced only once.
+// Its fragment is either a plain name defined by an id, or a JSON pointer (RFC 6901) addressing
+// any schema of the document, e.g #/definitions/a~1b, #/items or #/links/2/schema.
+// Refs to other documents are loaded with the RefLoader of the SchemaParser, once.
 // An error is returned if the ref or it doesn't point to a schema.
 func (sp *SchemaParser) ResolveSchemaRef(schemaRef string, relSchema *Schema) (*Schema, error) {
-	// Absolute ref
-	if strings.HasPrefix(schemaRef, "#/") {
-		keys := strings.Split(schemaRef[2:], "/")
-
-		schv := reflect.ValueOf(sp.RootSchema)
-		for _, key := range keys {
-			// Dereference pointers
-			for schv.Kind() == reflect.Ptr || schv.Kind() == reflect.Interface {
-				schv = schv.Elem()
-			}
-			switch t := schv.Interface().(type) {
-			case Schema:
-				fv := schv.FieldByName(capitalize(key))
-				schv = fv
-			case map[string]*Schema:
-				ukey, err := unescapePctEnc(key)
-				if err != nil {
-					return nil, err
-				}
-				s, ok := t[string(ukey)]
-				if !ok {
-					return nil, InvalidSchemaRefError{Ref: schemaRef, Msg: "invalid ref"}
-				}
-				schv = reflect.ValueOf(s)
-			default:
-				return nil, InvalidSchemaRefError{Ref: schemaRef, Msg: "value is not a valid Schema"}
-			}
+	schema, err := sp.resolveSchemaRef(schemaRef, relSchema)
+	if err != nil && relSchema.Ref == schemaRef {
+		return nil, sp.locateError(err, relSchema, "/$ref")
+	}
+	// Otherwise, the ref comes from elsewhere, like the href of a link: the caller knows where.
+	return schema, err
+}
+
+func (sp *SchemaParser) resolveSchemaRef(schemaRef string, relSchema *Schema) (*Schema, error) {
+	// Name of a property of relSchema
+	if propertySchema, ok := relSchema.Properties[schemaRef]; ok && !strings.Contains(schemaRef, "#") {
+		return propertySchema, nil
+	}
+	uri, err := resolveURI(sp.baseURI(relSchema), schemaRef)
+	if err != nil {
+		return nil, InvalidSchemaRefError{Ref: schemaRef, Msg: err.Error()}
+	}
+	docURI, fragment := splitFragment(uri)
+	doc := sp.RootSchema
+	if docURI != sp.rootURI() {
+		doc, err = sp.loadDocument(docURI, schemaRef)
+		if err != nil {
+			return nil, err
 		}
-		// This has been checked in the for loop
-		schema, ok := schv.Interface().(*Schema)
+	}
+	switch {
+	case fragment == "":
+		return doc, nil
+	case !strings.HasPrefix(fragment, "/"):
+		// Plain name fragment, defined by an id
+		schema, ok := sp.schemaIDs[docURI+"#"+fragment]
 		if !ok {
-			return nil, InvalidSchemaRefError{Ref: schemaRef, Msg: "value is not a valid Schema"}
+			return nil, InvalidSchemaRefError{Ref: schemaRef, Msg: "no schema with this id"}
 		}
 		return schema, nil
 	}
-	propertySchema, ok := relSchema.Properties[schemaRef]
-	if !ok {
-		return nil, InvalidSchemaRefError{Ref: schemaRef, Msg: "value is not a valid Schema"}
+
+	// The fragment is a JSON pointer, percent-encoded in the URI.
+	pointer, err := unescapePctEnc(fragment)
+	if err != nil {
+		return nil, InvalidSchemaRefError{Ref: schemaRef, Msg: err.Error()}
 	}
-	return propertySchema, nil
+	schema, err := resolvePointer(doc, string(pointer))
+	if err != nil {
+		return nil, InvalidSchemaRefError{Ref: schemaRef, Msg: err.Error()}
+	}
+	if schema == nil {
+		if _, ok := doc.locations[string(pointer)]; ok {
+			return nil, InvalidSchemaRefError{Ref: schemaRef, Msg: "value is not a valid Schema"}
+		}
+		return nil, InvalidSchemaRefError{Ref: schemaRef, Msg: "invalid ref"}
+	}
+	return schema, nil
 }
 
 // JSONTypeFromSchema parses a JSON Schema and returns a value satisfying the jsonType interface.
@@ -1016,6 +2241,9 @@ func (sp *SchemaParser) JSONTypeFromSchema(defaultName string, schema *Schema, r
 		if !(err == nil && jt != nil && jt.Ref() != "") {
 			return
 		}
+		if o, ok := jt.(JSONObject); ok && o.recursive {
+			return
+		}
 		if sp.refJSONTypeMap == nil {
 			sp.refJSONTypeMap = make(map[string]JSONType)
 		}
@@ -1033,75 +2261,586 @@ func (sp *SchemaParser) JSONTypeFromSchema(defaultName string, schema *Schema, r
 
 	name := defaultName
 	if ref != "" {
-		name = ref2name(ref)
+		name = sp.namer().RefName(ref)
+	}
+	if resSchema.GoName != "" {
+		name = resSchema.GoName
 	}
 
-	switch t := resSchema.Type; {
+	if sp.parsing == nil {
+		sp.parsing = make(map[*Schema]bool)
+	}
+	_, inProgress := sp.parsing[resSchema]
+	if inProgress && ref != "" {
+		// The type refers to itself: it's resolved once parsed.
+		sp.parsing[resSchema] = true
+		return JSONObject{Name: name, ref: ref, recursive: true}, nil
+	}
+	if !inProgress {
+		sp.parsing[resSchema] = false
+		defer sp.endParsing(resSchema, ref, &jt, &err)
+	}
+
+	if len(resSchema.Type) > 1 {
+		jt, err = sp.jsonMultiTypeFromSchema(name, resSchema, ref)
+		return
+	}
+	if goType, ok := sp.GoTypes[ref]; ok && ref != "" {
+		jt = JSONGoType{ref: ref, GoType: goType}
+		return
+	}
+	if goType, ok := sp.GoTypes[resSchema.Format]; ok && resSchema.Format != "" {
+		jt = JSONGoType{ref: ref, GoType: goType}
+		return
+	}
+	// A const string is an enum of one value. The type of a const may be implied.
+	// Other consts, e.g objects, are ignored.
+	t, enumValues := resSchema.Type.String(), resSchema.Enum
+	if c := constValue(resSchema, sp.draft(resSchema)); constType(c) != "" {
+		ct := constType(c)
+		switch {
+		case t == "":
+			t = ct
+		case t != ct && !(t == "number" && ct == "integer"):
+			return nil, sp.schemaError(schema, "/const", fmt.Sprintf("const %v is not of type %s", c, t))
+		}
+		if v, ok := c.(string); ok {
+			enumValues = []string{v}
+		}
+	}
+	switch {
+	case len(resSchema.OneOf) > 0 || len(resSchema.AnyOf) > 0:
+		jt, err = sp.jsonUnionFromSchema(name, resSchema, ref)
+		return
 	case t == "object" || t == "": // default value is "object"
+		var (
+			additionalType        JSONType
+			additionalConstraints Constraints
+			additionalPattern     string
+		)
+		additionalType, additionalConstraints, additionalPattern, err = sp.additionalPropertiesType(name, resSchema)
+		if err != nil {
+			return nil, err
+		}
+		if additionalType != nil && len(resSchema.Properties) == 0 && len(resSchema.AllOf) == 0 {
+			jt = JSONMap{ref: ref, Values: additionalType, ValueConstraints: additionalConstraints, KeyPattern: additionalPattern}
+			return
+		}
+		required := make(map[string]bool)
+		for _, propertyName := range resSchema.Required {
+			required[propertyName] = true
+		}
 		var fields JSONFieldList
-		for propertyName, propertySchema := range resSchema.Properties {
+		for _, propertyName := range resSchema.PropertyNames() {
+			propertySchema := resSchema.Properties[propertyName]
 			resPropertySchema, err := sp.ResolveSchema(propertySchema)
 			if err != nil {
 				return nil, err
 			}
-			typ, err := sp.JSONTypeFromSchema(symbolName(propertyName), resPropertySchema, propertySchema.Ref)
+			typeName := sp.namer().SymbolName(propertyName)
+			if sp.refOf(propertySchema) == "" && resPropertySchema.GoName == "" && sp.isEnumSchema(resPropertySchema) {
+				// Inline enums, like the discriminators of variants, are named after their object too.
+				typeName = name + typeName
+			}
+			typ, err := sp.JSONTypeFromSchema(typeName, resPropertySchema, sp.refOf(propertySchema))
+			if err != nil {
+				return nil, err
+			}
+			constraints, err := sp.typeConstraints(typ, resPropertySchema)
 			if err != nil {
 				return nil, err
 			}
+			description := propertySchema.Description
+			if description == "" {
+				description = resPropertySchema.Description
+			}
+			defaultValue := propertySchema.Default
+			if defaultValue == nil {
+				defaultValue = resPropertySchema.Default
+			}
+			if err := sp.checkDefault(typ, defaultValue); err == errUnsupportedDefault {
+				defaultValue = nil
+			} else if err != nil {
+				return nil, sp.schemaError(propertySchema, "/default", fmt.Sprintf("default value of property %q: %v", propertyName, err))
+			} else if err := sp.checkDefaultConstraints(typ, constraints, defaultValue); err != nil {
+				return nil, sp.schemaError(propertySchema, "/default", fmt.Sprintf("default value of property %q: %v", propertyName, err))
+			}
 			fields = append(fields, JSONField{
-				Name: propertyName,
-				Type: typ,
+				Name:        propertyName,
+				Type:        typ,
+				Required:    required[propertyName],
+				Constraints: constraints,
+				Description: description,
+				ReadOnly:    propertySchema.ReadOnly || resPropertySchema.ReadOnly,
+				Default:     defaultValue,
+				GoName:      propertySchema.GoName,
 			})
 		}
-		sort.Sort(fields)
+		if sp.SortFields {
+			sort.Sort(fields)
+		}
 
-		jt = JSONObject{
-			Name:   name,
-			ref:    ref,
-			Fields: fields,
+		obj := JSONObject{
+			Name:                            name,
+			ref:                             ref,
+			Fields:                          fields,
+			AdditionalProperties:            additionalType,
+			AdditionalPropertiesConstraints: additionalConstraints,
+			AdditionalPropertiesPattern:     additionalPattern,
+		}
+		if len(resSchema.AllOf) > 0 {
+			obj, err = sp.composeAllOf(resSchema, obj)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, f := range obj.Fields {
+			if obj.AdditionalProperties != nil && sp.goFieldName(f) == "AdditionalProperties" {
+				return nil, sp.schemaError(schema, "/additionalProperties", fmt.Sprintf("schema: property %q of %s conflicts with its additional properties", f.Name, name))
+			}
 		}
+		obj.Description = resSchema.Description
+		jt = obj
 		return
 	case t == "array":
 		items := resSchema.Items
 		if items == nil {
-			return nil, InvalidSchemaError{*schema, "schema: missing items property for type array"}
+			return nil, sp.schemaError(schema, "", "schema: missing items property for type array")
 		}
 		resItems, err := sp.ResolveSchema(items)
 		if err != nil {
 			return nil, err
 		}
 
-		jst, err := sp.JSONTypeFromSchema(fmt.Sprintf("%sOne", name), resItems, items.Ref)
+		jst, err := sp.JSONTypeFromSchema(sp.namer().InnerTypeName(name, "One", 0), resItems, sp.refOf(items))
+		if err != nil {
+			return nil, err
+		}
+		itemConstraints, err := sp.typeConstraints(jst, resItems)
 		if err != nil {
 			return nil, err
 		}
-		return JSONArray{Name: name, ref: ref, Items: jst}, nil
+		return JSONArray{Name: name, ref: ref, Items: jst, ItemConstraints: itemConstraints}, nil
+	case t == "string" && len(enumValues) > 0:
+		enum := JSONEnum{Name: name, ref: ref, Values: enumValues, Description: resSchema.Description}
+		constNames := make(map[string]string)
+		for _, v := range enum.Values {
+			constName := enum.ConstName(v)
+			if pv, ok := constNames[constName]; ok {
+				return nil, sp.schemaError(schema, "/enum", fmt.Sprintf("enum values %q and %q have the same Go name %s", pv, v, constName))
+			}
+			constNames[constName] = v
+		}
+		jt = enum
+		return
 	case t == "string" && resSchema.Format == "date-time":
 		jt = JSONDateTime{ref: ref}
 		return
 	case t == "string":
-		jt = JSONString{ref: ref}
+		jt = JSONString{ref: ref, Format: knownFormat(resSchema)}
 		return
 	case t == "boolean":
 		jt = JSONBoolean{ref: ref}
 		return
 	case t == "integer":
-		jt = JSONInteger{ref: ref}
+		jt = JSONInteger{ref: ref, Format: knownFormat(resSchema)}
 		return
 	case t == "number":
-		jt = JSONNumber{ref: ref}
+		jt = JSONNumber{ref: ref, Format: knownFormat(resSchema)}
 		return
 	case t == "null": // ?
 		jt = JSONNull{ref: ref}
 		return
 	default:
-		err = InvalidSchemaError{*schema, fmt.Sprintf("unknown type %q", resSchema.Type)}
+		err = sp.schemaError(schema, "/type", fmt.Sprintf("unknown type %q", t))
+		return
+	}
+}
+
+// endParsing ends the parsing of the type of schema, whose result is *jt and *err.
+// If the type refers to itself, it must be a named struct, through a pointer, a slice or a map.
+func (sp *SchemaParser) endParsing(schema *Schema, ref string, jt *JSONType, err *error) {
+	recursive := sp.parsing[schema]
+	delete(sp.parsing, schema)
+	if *err != nil || !recursive {
+		return
+	}
+	switch t := nonNullType(*jt).(type) {
+	case JSONObject:
+		if !t.isEmpty() {
+			return
+		}
+	case JSONUnion:
 		return
 	}
+	*jt, *err = nil, sp.schemaError(schema, "", fmt.Sprintf("schema: %s refers to itself, but only an object or a union can be recursive", ref))
+}
+
+// composeAllOf composes the allOf members of schema with obj, the object of its own properties.
+//
+// A member which is a $ref to an object is embedded, unless some of its properties are also
+// declared by another member or by schema, or it has additional properties; the properties of
+// the other members are merged into obj. A property declared several times with different types is an error.
+func (sp *SchemaParser) composeAllOf(schema *Schema, obj JSONObject) (JSONObject, error) {
+	name, fields := obj.Name, obj.Fields
+	members := make([]JSONObject, 0, len(schema.AllOf))
+	// propertyCount counts the declarations of each property, schema's own included.
+	propertyCount := make(map[string]int)
+	propertyTypes := make(map[string]string)
+	declare := func(f JSONField) error {
+		goType, _ := sp.jsonFieldGoType(JSONField{Type: f.Type, Required: true})
+		if pGoType, ok := propertyTypes[f.Name]; ok && pGoType != goType {
+			return sp.schemaError(schema, "/allOf", fmt.Sprintf("schema: allOf of %s declares property %q as both %s and %s", name, f.Name, pGoType, goType))
+		}
+		propertyTypes[f.Name] = goType
+		propertyCount[f.Name]++
+		return nil
+	}
+	for _, f := range fields {
+		if err := declare(f); err != nil {
+			return JSONObject{}, err
+		}
+	}
+	for i := range schema.AllOf {
+		memberSchema := &schema.AllOf[i]
+		typ, err := sp.JSONTypeFromSchema(sp.namer().InnerTypeName(name, "Part", i+1), memberSchema, sp.refOf(memberSchema))
+		if err != nil {
+			return JSONObject{}, err
+		}
+		member, ok := typ.(JSONObject)
+		if !ok {
+			return JSONObject{}, sp.schemaError(memberSchema, "", fmt.Sprintf("schema: allOf member %d of %s is not an object", i+1, name))
+		}
+		for _, f := range member.allFields() {
+			if err := declare(f); err != nil {
+				return JSONObject{}, err
+			}
+		}
+		members = append(members, member)
+	}
+
+	required := make(map[string]bool)
+	for _, propertyName := range schema.Required {
+		required[propertyName] = true
+	}
+	fieldIndex := make(map[string]int)
+	for i, f := range fields {
+		fieldIndex[f.Name] = i
+	}
+	// isShared returns true if one of the fields is declared elsewhere.
+	isShared := func(fl JSONFieldList) bool {
+		for _, f := range fl {
+			if propertyCount[f.Name] > 1 {
+				return true
+			}
+		}
+		return false
+	}
+	var embedded []JSONType
+	for _, member := range members {
+		memberFields := member.allFields()
+		if member.ref != "" && len(memberFields) > 0 && member.AdditionalProperties == nil && !isShared(memberFields) {
+			embedded = append(embedded, member)
+			continue
+		}
+		if obj.AdditionalProperties == nil {
+			obj.AdditionalProperties = member.AdditionalProperties
+			obj.AdditionalPropertiesConstraints = member.AdditionalPropertiesConstraints
+			obj.AdditionalPropertiesPattern = member.AdditionalPropertiesPattern
+		}
+		for _, f := range memberFields {
+			f.Required = f.Required || required[f.Name]
+			i, ok := fieldIndex[f.Name]
+			if !ok {
+				fieldIndex[f.Name] = len(fields)
+				fields = append(fields, f)
+				continue
+			}
+			fields[i].Required = fields[i].Required || f.Required
+			if fields[i].Constraints.IsZero() {
+				fields[i].Constraints = f.Constraints
+			}
+			if fields[i].Default == nil {
+				fields[i].Default = f.Default
+			}
+		}
+	}
+	if sp.SortFields {
+		sort.Sort(fields)
+	}
+	obj.Fields = fields
+	obj.Embedded = embedded
+	return obj, nil
+}
+
+// additionalPropertiesType returns the type of the properties of schema matching its patternProperties,
+// or described by its additionalProperties. nil is returned if they describe none.
+//
+// If several types are found, the properties may hold any value. The validation keywords
+// of the properties are returned if a single schema describes them, with its pattern if it's
+// the one of a patternProperties: the other properties aren't checked against them.
+func (sp *SchemaParser) additionalPropertiesType(name string, schema *Schema) (JSONType, Constraints, string, error) {
+	var valueSchemas []*Schema
+	patterns := make([]string, 0, len(schema.PatternProperties))
+	for pattern := range schema.PatternProperties {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, Constraints{}, "", sp.schemaError(schema, "/patternProperties/"+pointerToken(pattern), fmt.Sprintf("invalid patternProperties pattern %q: %v", pattern, err))
+		}
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		valueSchemas = append(valueSchemas, schema.PatternProperties[pattern])
+	}
+	switch ap := schema.AdditionalProperties.(type) {
+	case nil:
+	case bool:
+		if ap {
+			valueSchemas = append(valueSchemas, &Schema{})
+		}
+	case map[string]interface{}:
+		valueSchema, err := schema.AdditionalPropertiesSchema()
+		if err != nil {
+			return nil, Constraints{}, "", sp.schemaError(schema, "/additionalProperties", fmt.Sprintf("invalid additionalProperties: %v", err))
+		}
+		valueSchemas = append(valueSchemas, valueSchema)
+	default:
+		return nil, Constraints{}, "", sp.schemaError(schema, "/additionalProperties", fmt.Sprintf("invalid additionalProperties %v", ap))
+	}
+
+	var valuesType JSONType
+	for _, valueSchema := range valueSchemas {
+		typ, err := sp.JSONTypeFromSchema(sp.namer().InnerTypeName(name, "Value", 0), valueSchema, sp.refOf(valueSchema))
+		if err != nil {
+			return nil, Constraints{}, "", err
+		}
+		if valuesType == nil {
+			valuesType = typ
+			continue
+		}
+		if sp.JSONToGoType(valuesType, false) != sp.JSONToGoType(typ, false) {
+			return JSONObject{Name: sp.namer().InnerTypeName(name, "Value", 0)}, Constraints{}, "", nil
+		}
+	}
+	if len(valueSchemas) != 1 {
+		// The values of several schemas have several sets of constraints.
+		return valuesType, Constraints{}, "", nil
+	}
+	valueSchema, err := sp.ResolveSchema(valueSchemas[0])
+	if err != nil {
+		return nil, Constraints{}, "", err
+	}
+	constraints, err := sp.typeConstraints(valuesType, valueSchema)
+	if err != nil {
+		return nil, Constraints{}, "", err
+	}
+	var keyPattern string
+	if len(patterns) == 1 {
+		keyPattern = patterns[0]
+	}
+	return valuesType, constraints, keyPattern, nil
+}
+
+// jsonUnionFromSchema returns the JSONUnion of the oneOf or anyOf variants of schema.
+//
+// The null variants make it a JSONNullable, like a type listed with "null": of the other variant if
+// there's only one, of the union of the other variants otherwise.
+func (sp *SchemaParser) jsonUnionFromSchema(name string, schema *Schema, ref string) (JSONType, error) {
+	if len(schema.OneOf) > 0 && len(schema.AnyOf) > 0 {
+		return nil, sp.schemaError(schema, "", "schema: oneOf and anyOf can't be used together")
+	}
+	union := JSONUnion{
+		Name:          name,
+		ref:           ref,
+		OneOf:         len(schema.OneOf) > 0,
+		Discriminator: schema.Discriminator,
+		Description:   schema.Description,
+	}
+	variantSchemas := schema.OneOf
+	if !union.OneOf {
+		variantSchemas = schema.AnyOf
+	}
+	nonNullSchemas, err := sp.nonNullVariants(variantSchemas)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(nonNullSchemas) == len(variantSchemas):
+	case len(nonNullSchemas) == 0:
+		return JSONNull{ref: ref}, nil
+	case len(nonNullSchemas) == 1:
+		typ, err := sp.JSONTypeFromSchema(name, nonNullSchemas[0], sp.refOf(nonNullSchemas[0]))
+		if err != nil {
+			return nil, err
+		}
+		return JSONNullable{Value: typ}, nil
+	}
+	fieldNames := make(map[string]bool)
+	for i := range variantSchemas {
+		variantSchema := &variantSchemas[i]
+		if !containsSchema(nonNullSchemas, variantSchema) {
+			continue
+		}
+		typ, err := sp.JSONTypeFromSchema(sp.namer().InnerTypeName(name, "Variant", i+1), variantSchema, sp.refOf(variantSchema))
+		if err != nil {
+			return nil, err
+		}
+		fieldName := sp.unionVariantName(typ)
+		if fieldNames[fieldName] {
+			return nil, sp.schemaError(schema, "", fmt.Sprintf("schema: variants of %s have the same Go name %s", name, fieldName))
+		}
+		fieldNames[fieldName] = true
+		union.Variants = append(union.Variants, typ)
+
+		if union.Discriminator == "" {
+			continue
+		}
+		v, err := discriminatorValue(typ, union.Discriminator)
+		if err != nil {
+			return nil, sp.schemaError(variantSchema, "", fmt.Sprintf("schema: variant %s of %s: %s", fieldName, name, err))
+		}
+		for _, pv := range union.DiscriminatorValues {
+			if pv == v {
+				return nil, sp.schemaError(schema, "", fmt.Sprintf("schema: variants of %s have the same %s %q", name, union.Discriminator, v))
+			}
+		}
+		union.DiscriminatorValues = append(union.DiscriminatorValues, v)
+	}
+	if len(nonNullSchemas) < len(variantSchemas) {
+		return JSONNullable{Value: union}, nil
+	}
+	return union, nil
+}
+
+// isEnumSchema returns true if the type of the schema s is an enum: its type is string,
+// or string and null, and it has an enum or a string const.
+func (sp *SchemaParser) isEnumSchema(s *Schema) bool {
+	if len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		return false
+	}
+	var types SchemaType
+	for _, t := range s.Type {
+		if t != "null" {
+			types = append(types, t)
+		}
+	}
+	if _, ok := constValue(s, sp.draft(s)).(string); ok {
+		return len(types) == 0 || types.String() == "string"
+	}
+	return len(s.Enum) > 0 && types.String() == "string"
+}
+
+// nonNullVariants returns the variants of a oneOf or anyOf which aren't of the null type.
+func (sp *SchemaParser) nonNullVariants(variantSchemas []Schema) ([]*Schema, error) {
+	var nonNullSchemas []*Schema
+	for i := range variantSchemas {
+		resolved, err := sp.ResolveSchema(&variantSchemas[i])
+		if err != nil {
+			return nil, err
+		}
+		if len(resolved.Type) != 1 || resolved.Type[0] != "null" {
+			nonNullSchemas = append(nonNullSchemas, &variantSchemas[i])
+		}
+	}
+	return nonNullSchemas, nil
+}
+
+// containsSchema returns true if s is one of schemas.
+func containsSchema(schemas []*Schema, s *Schema) bool {
+	for _, schema := range schemas {
+		if schema == s {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonMultiTypeFromSchema returns the type of a schema whose type is a list of types.
+//
+// A type listed with "null" is a JSONNullable of this type.
+// Several types are a union of the types, holding the first of them matching the JSON value.
+func (sp *SchemaParser) jsonMultiTypeFromSchema(name string, schema *Schema, ref string) (JSONType, error) {
+	var types SchemaType
+	var nullable bool
+	for _, t := range schema.Type {
+		if t == "null" {
+			nullable = true
+			continue
+		}
+		types = append(types, t)
+	}
+	if len(types) == 0 {
+		return JSONNull{ref: ref}, nil
+	}
+	if nullable {
+		nonNullSchema := *schema
+		nonNullSchema.Type = types
+		typ, err := sp.JSONTypeFromSchema(name, &nonNullSchema, ref)
+		if err != nil {
+			return nil, sp.locateError(err, schema, "")
+		}
+		return JSONNullable{Value: typ}, nil
+	}
+
+	union := JSONUnion{Name: name, ref: ref, Description: schema.Description}
+	for _, t := range types {
+		variantSchema := *schema
+		variantSchema.Type = SchemaType{t}
+		variantSchema.Description = ""
+		typ, err := sp.JSONTypeFromSchema(name+sp.namer().SymbolName(t), &variantSchema, "")
+		if err != nil {
+			return nil, sp.locateError(err, schema, "")
+		}
+		for _, variant := range union.Variants {
+			if sp.unionVariantName(variant) == sp.unionVariantName(typ) {
+				return nil, sp.schemaError(schema, "/type", fmt.Sprintf("schema: type %s is listed twice", t))
+			}
+		}
+		union.Variants = append(union.Variants, typ)
+	}
+	return union, nil
+}
+
+// unionVariantName returns the name identifying the variant typ in its union.
+func (sp *SchemaParser) unionVariantName(typ JSONType) string {
+	typ = nonNullType(typ)
+	if g, ok := typ.(JSONGoType); ok {
+		_, goType := splitGoType(g.GoType)
+		return sp.namer().SymbolName(strings.TrimLeft(goType[strings.LastIndex(goType, ".")+1:], "*[]"))
+	}
+	if n, ok := typ.(TypeNamer); ok {
+		return n.TypeName()
+	}
+	return sp.namer().SymbolName(typ.Type())
+}
+
+// discriminatorValue returns the value of the discriminator property of the object variant typ.
+//
+// It's the value of the property if it's an enum with a single value,
+// and the name of the definition of the variant otherwise.
+func discriminatorValue(typ JSONType, discriminator string) (string, error) {
+	jo, ok := typ.(JSONObject)
+	if !ok {
+		return "", fmt.Errorf("a discriminated variant must be an object")
+	}
+	for _, f := range jo.allFields() {
+		if f.Name != discriminator {
+			continue
+		}
+		if e, ok := f.Type.(JSONEnum); ok && len(e.Values) == 1 {
+			return e.Values[0], nil
+		}
+		if jo.ref == "" {
+			return "", fmt.Errorf("property %q must be an enum with a single value", discriminator)
+		}
+		refParts := strings.Split(jo.ref, "/")
+		return refParts[len(refParts)-1], nil
+	}
+	return "", fmt.Errorf("missing discriminator property %q", discriminator)
 }
 
 // RouteParamsFromLink parses the link to return a slice of RouteParam,
-// dereferenced using the schema from which the link originates.
+// dereferenced using the link's hrefSchema or the schema from which the link originates.
 func (sp *SchemaParser) RouteParamsFromLink(link *Link, schema *Schema) ([]RouteParam, error) {
 	var routeParams []RouteParam
 	vars, err := varsFromHRef(link.HRef)
@@ -1110,17 +2849,13 @@ func (sp *SchemaParser) RouteParamsFromLink(link *Link, schema *Schema) ([]Route
 	}
 
 	for _, v := range vars {
-		// Strip leading #/definitions/
-		name := strings.Replace(v, "#/definitions/", "", 1)
-		// Hyphenify the remaining ones
-		// TODO allow customize this
-		name = strings.Replace(name, "/definitions/", "-", -1)
-		vname := toUpperAfterAny(name, "-")
-
-		varRefSchema, err := sp.ResolveSchemaRef(v, schema)
+		name := definitionsPathName(v)
+
+		varRefSchema, varRef, err := sp.hrefVarSchema(v, link, schema)
 		if err != nil {
 			return nil, err
 		}
+		vname := sp.paramName(name, varRefSchema)
 		varRefSchema, err = sp.ResolveSchema(varRefSchema)
 		if err != nil {
 			return nil, err
@@ -1128,7 +2863,7 @@ func (sp *SchemaParser) RouteParamsFromLink(link *Link, schema *Schema) ([]Route
 
 		// FIXME we rely on absolute $ref to construct the name here
 		names := strings.Split(v, "/")
-		typ, err := sp.JSONTypeFromSchema(names[len(names)-1], varRefSchema, v)
+		typ, err := sp.JSONTypeFromSchema(sp.namer().SymbolName(names[len(names)-1]), varRefSchema, varRef)
 		if err != nil {
 			return nil, err
 		}
@@ -1142,36 +2877,147 @@ func (sp *SchemaParser) RouteParamsFromLink(link *Link, schema *Schema) ([]Route
 	return routeParams, nil
 }
 
+// hrefSchemaProperty returns the property v of the hrefSchema of link, or nil if it has none.
+func (sp *SchemaParser) hrefSchemaProperty(link *Link, v string) (*Schema, error) {
+	if link.HRefSchema == nil {
+		return nil, nil
+	}
+	hrefSchema, err := sp.ResolveSchema(link.HRefSchema)
+	if err != nil {
+		return nil, err
+	}
+	return hrefSchema.Properties[v], nil
+}
+
+// hrefVarSchema returns the schema of the href variable v of link, and its canonical ref.
+// The variable is described by the property v of the link's hrefSchema, if any,
+// or else by the schema which v refers to, relative to schema.
+func (sp *SchemaParser) hrefVarSchema(v string, link *Link, schema *Schema) (*Schema, string, error) {
+	property, err := sp.hrefSchemaProperty(link, v)
+	if err != nil {
+		return nil, "", err
+	}
+	if property != nil {
+		return property, sp.refOf(property), nil
+	}
+	varRefSchema, err := sp.ResolveSchemaRef(v, schema)
+	if err != nil {
+		return nil, "", err
+	}
+	return varRefSchema, sp.canonicalRef(v, schema), nil
+}
+
+// QueryTypeFromLink parses the link to return the type of its query string parameters,
+// dereferenced using the schema from which the link originates.
+//
+// The type is an object named name, whose fields are the properties of the link's schema, if it describes
+// the query string, and the variables of the {?var} expansions of its href.
+// Variables which are neither a property of the link's hrefSchema or of schema, nor a $ref, are strings.
+// nil is returned if the link has no query string parameters.
+func (sp *SchemaParser) QueryTypeFromLink(name string, link *Link, schema *Schema) (JSONType, error) {
+	jo := JSONObject{Name: name}
+	if link.Schema != nil && link.SchemaDescribesQuery() {
+		typ, err := sp.JSONTypeFromSchema(name, link.Schema, sp.refOf(link.Schema))
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		jo, ok = typ.(JSONObject)
+		if !ok || len(jo.Embedded) > 0 || jo.AdditionalProperties != nil {
+			return nil, sp.schemaError(link.Schema, "", fmt.Sprintf("link %s: the schema of a %s link must be an object with properties only", link.HRef, link.Method))
+		}
+	}
+
+	vars, err := queryVarsFromHRef(link.HRef)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vars {
+		names := strings.Split(v, "/")
+		field := JSONField{Name: names[len(names)-1], Type: JSONString{}}
+		if jo.hasField(field.Name) {
+			continue
+		}
+		hrefProperty, err := sp.hrefSchemaProperty(link, v)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := schema.Properties[v]; ok || hrefProperty != nil || strings.Contains(v, "#") {
+			varRefSchema, varRef, err := sp.hrefVarSchema(v, link, schema)
+			if err != nil {
+				return nil, err
+			}
+			typ, err := sp.JSONTypeFromSchema(sp.namer().SymbolName(field.Name), varRefSchema, varRef)
+			if err != nil {
+				return nil, err
+			}
+			field.Type = typ
+		}
+		sp.logf(" --> link %s: discovered query param %s", link.HRef, field.Name)
+		// The type is no longer the one of the link's schema.
+		jo.Name, jo.ref = name, ""
+		jo.Fields = append(jo.Fields, field)
+	}
+
+	if len(jo.Fields) == 0 {
+		return nil, nil
+	}
+	for _, f := range jo.Fields {
+		if !sp.isQueryParamType(f.Type) {
+			return nil, sp.schemaError(schema, "", fmt.Sprintf("link %s: query parameter %q must be a string, number, integer, boolean or enum, or an array of those", link.HRef, f.Name))
+		}
+	}
+	return jo, nil
+}
+
+// isQueryParamType returns true if the values of a query param of type typ can be decoded
+// from the query string.
+func (sp *SchemaParser) isQueryParamType(typ JSONType) bool {
+	typ = sp.ResolveType(nonNullType(typ))
+	if a, ok := typ.(JSONArray); ok {
+		typ = sp.ResolveType(nonNullType(a.Items))
+	}
+	switch typ.(type) {
+	case JSONString, JSONInteger, JSONNumber, JSONBoolean, JSONDateTime, JSONEnum:
+		return true
+	}
+	return false
+}
+
 // checkNamedTypeRedefinitions analyzes the routes just parsed by the SchemaParser and returns the
-// redefinitions of named types it finds.
+// redefinitions of named types it finds, nested ones included: e.g two inline objects of properties
+// with the same name, but different properties.
 func (sp *SchemaParser) checkNamedTypeRedefinitions(routes Routes) (map[string][]JSONTypeNamer, bool) {
 	noRedefinitions := true
 	redefinitions := make(map[string][]JSONTypeNamer)
 	definitions := make(map[string]JSONTypeNamer)
 
 	for _, route := range routes {
-		for _, typ := range []JSONType{route.InType, route.OutType} {
-			if typ == nil {
-				continue
+		types := []JSONType{route.InType, route.OutType, route.QueryType}
+		for _, rp := range route.RouteParams {
+			if _, ok := rp.Type.(JSONEnum); ok {
+				types = append(types, rp.Type)
 			}
-			//routes.walkType(typ, func(jt JSONType) {
-			jt := typ
-			jtn, ok := jt.(JSONTypeNamer)
-			if !ok {
+		}
+		for _, typ := range types {
+			if typ == nil {
 				continue
 			}
-			tn := jtn.TypeName()
-			if fjtn, ok := definitions[tn]; !ok {
-				definitions[tn] = jtn
-				redefinitions[tn] = []JSONTypeNamer{jtn}
-			} else {
-				// it's okay to compare with the string representation of the type.
-				if sp.JSONToGoType(jtn, true) != sp.JSONToGoType(fjtn, true) {
+			routes.walkType(typ, func(jt JSONType) {
+				jtn, ok := jt.(JSONTypeNamer)
+				if _, isArray := jt.(JSONArray); !ok || isArray {
+					// No type is defined for an array, its name is unused.
+					return
+				}
+				tn := jtn.TypeName()
+				if fjtn, ok := definitions[tn]; !ok {
+					definitions[tn] = jtn
+					redefinitions[tn] = []JSONTypeNamer{jtn}
+				} else if !sp.sameTypeDefinition(jtn, fjtn) {
 					redefinitions[tn] = append(redefinitions[tn], jtn)
 					noRedefinitions = false
 				}
-			}
-			//})
+			})
 		}
 	}
 	if !noRedefinitions {
@@ -1184,6 +3030,18 @@ func (sp *SchemaParser) checkNamedTypeRedefinitions(routes Routes) (map[string][
 	return redefinitions, noRedefinitions
 }
 
+// sameTypeDefinition returns true if the named types a and b have the same Go definition.
+func (sp *SchemaParser) sameTypeDefinition(a JSONTypeNamer, b JSONTypeNamer) bool {
+	// The values of an enum are its constants.
+	ae, aIsEnum := sp.ResolveType(a).(JSONEnum)
+	be, bIsEnum := sp.ResolveType(b).(JSONEnum)
+	if aIsEnum && bIsEnum && !reflect.DeepEqual(ae.Values, be.Values) {
+		return false
+	}
+	// it's okay to compare with the string representation of the type.
+	return sp.JSONToGoType(a, true) == sp.JSONToGoType(b, true)
+}
+
 // TypeRedefinitionError represents a named type which has been redefined one or more times with a different definition.
 type TypeRedefinitionError struct {
 	Name   string
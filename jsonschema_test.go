@@ -6,6 +6,7 @@ import (
 	"os"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 )
 
@@ -151,15 +152,504 @@ func TestParseSimpleJSONStruct(t *testing.T) {
 	}
 	sort.Sort(expectedObj.Fields)
 
-	sp := SchemaParser{RootSchema: schema}
+	sp := SchemaParser{RootSchema: schema, SortFields: true}
 	obj, err := sp.JSONTypeFromSchema(schema.Title, schema, "")
 	if err != nil {
 		t.Error(err)
 		return
 	}
-	if !reflect.DeepEqual(expectedObj, obj) {
-		t.Errorf("expected %#v, got %#v", expectedObj, obj)
-		return
+	if !reflect.DeepEqual(expectedObj, obj) {
+		t.Errorf("expected %#v, got %#v", expectedObj, obj)
+		return
+	}
+}
+
+func TestParseEnum(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "element": {
+            "type": "string",
+            "enum": ["fire", "water", "dark-arts"]
+        }
+    },
+    "properties": {
+        "element": {
+            "$ref": "#/definitions/element"
+        },
+        "mood": {
+            "type": "string",
+            "enum": ["calm", "angry"]
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+
+	expectedObj := JSONObject{
+		Name: "Spell",
+		Fields: []JSONField{
+			{Name: "element", Type: JSONEnum{Name: "Element", ref: "#/definitions/element", Values: []string{"fire", "water", "dark-arts"}}},
+			{Name: "mood", Type: JSONEnum{Name: "SpellMood", Values: []string{"calm", "angry"}}},
+		},
+	}
+
+	sp := SchemaParser{RootSchema: schema}
+	obj, err := sp.JSONTypeFromSchema("Spell", schema, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(expectedObj, obj) {
+		t.Errorf("expected %#v, got %#v", expectedObj, obj)
+		return
+	}
+
+	tests := []struct {
+		Value     string
+		ConstName string
+	}{
+		{"fire", "ElementFire"},
+		{"dark-arts", "ElementDarkArts"},
+		{"dark arts/2", "ElementDarkArts2"},
+		{"", "ElementEmpty"},
+	}
+	for _, test := range tests {
+		if constName := (JSONEnum{Name: "Element"}).ConstName(test.Value); constName != test.ConstName {
+			t.Errorf("%q: expected %q, got %q", test.Value, test.ConstName, constName)
+		}
+	}
+}
+
+func TestParseEnumWithConflictingValues(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": "string",
+    "enum": ["dark-arts", "dark_arts"]
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := SchemaParser{RootSchema: schema}
+	_, err := sp.JSONTypeFromSchema("Element", schema, "")
+	if _, ok := err.(InvalidSchemaError); !ok {
+		t.Errorf("expected an InvalidSchemaError, got %#v", err)
+	}
+}
+
+func TestParseNullableEnum(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": ["string", "null"],
+    "enum": ["happy", "sad", null]
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := SchemaParser{RootSchema: schema}
+	typ, err := sp.JSONTypeFromSchema("Mood", schema, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// null isn't a value of the enum, but of the nullable type.
+	expectedType := JSONNullable{Value: JSONEnum{Name: "Mood", Values: []string{"happy", "sad"}}}
+	if !reflect.DeepEqual(expectedType, typ) {
+		t.Errorf("expected %#v, got %#v", expectedType, typ)
+	}
+}
+
+const allOfSpellsSchema = `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "base-resource": {
+            "required": ["id"],
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "etag": {
+                    "type": "string"
+                }
+            }
+        },
+        "named": {
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "spell": {
+            "allOf": [
+                {
+                    "$ref": "#/definitions/base-resource"
+                },
+                {
+                    "$ref": "#/definitions/named"
+                },
+                {
+                    "properties": {
+                        "name": {
+                            "type": "string",
+                            "maxLength": 10
+                        },
+                        "power": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            ],
+            "required": ["name"],
+            "properties": {
+                "element": {
+                    "type": "string"
+                }
+            },
+            "links": [
+                {
+                    "href": "/spells",
+                    "method": "POST",
+                    "rel": "create",
+                    "schema": {
+                        "$ref": "#/definitions/spell"
+                    }
+                }
+            ]
+        }
+    },
+    "properties": {
+        "spell": {
+            "$ref": "#/definitions/spell"
+        }
+    }
+}`
+
+func TestParseAllOf(t *testing.T) {
+	schema := getSchemaString(t, allOfSpellsSchema)
+	if t.Failed() {
+		return
+	}
+
+	maxLength := Constraints{MaxLength: 10}
+	expectedObj := JSONObject{
+		Name: "Spell",
+		ref:  "#/definitions/spell",
+		Embedded: []JSONType{
+			JSONObject{
+				Name: "BaseResource",
+				ref:  "#/definitions/base-resource",
+				Fields: JSONFieldList{
+					{Name: "etag", Type: JSONString{}},
+					{Name: "id", Type: JSONString{}, Required: true},
+				},
+			},
+		},
+		Fields: JSONFieldList{
+			{Name: "element", Type: JSONString{}},
+			// name is declared by 2 members, so they are merged rather than embedded.
+			{Name: "name", Type: JSONString{}, Required: true, Constraints: maxLength},
+			{Name: "power", Type: JSONInteger{}},
+		},
+	}
+
+	sp := SchemaParser{RootSchema: schema, SortFields: true}
+	obj, err := sp.JSONTypeFromSchema("Spell", schema.Definitions["spell"], "#/definitions/spell")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(expectedObj, obj) {
+		t.Errorf("expected %#v, got %#v", expectedObj, obj)
+		return
+	}
+}
+
+func TestParseAllOfWithConflictingProperties(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "allOf": [
+        {
+            "properties": {
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        {
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        }
+    ]
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := SchemaParser{RootSchema: schema}
+	_, err := sp.JSONTypeFromSchema("Spell", schema, "")
+	if _, ok := err.(InvalidSchemaError); !ok {
+		t.Errorf("expected an InvalidSchemaError, got %#v", err)
+	}
+}
+
+func TestParseMultiTypes(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": "object",
+    "required": ["name"],
+    "definitions": {
+        "caster": {
+            "type": ["object", "null"],
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "properties": {
+        "caster": {
+            "$ref": "#/definitions/caster"
+        },
+        "name": {
+            "type": ["string", "null"]
+        },
+        "power": {
+            "type": ["integer", "string"]
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	if !reflect.DeepEqual(SchemaType{"string", "null"}, schema.Properties["name"].Type) {
+		t.Errorf("expected a list of types, got %#v", schema.Properties["name"].Type)
+	}
+
+	expectedObj := JSONObject{
+		Name: "Spell",
+		Fields: JSONFieldList{
+			{Name: "caster", Type: JSONNullable{Value: JSONObject{
+				Name: "Caster",
+				ref:  "#/definitions/caster",
+				Fields: JSONFieldList{
+					{Name: "name", Type: JSONString{}},
+				},
+			}}},
+			{Name: "name", Type: JSONNullable{Value: JSONString{}}, Required: true},
+			{Name: "power", Type: JSONUnion{
+				Name:     "Power",
+				Variants: []JSONType{JSONInteger{}, JSONString{}},
+			}},
+		},
+	}
+	sp := SchemaParser{RootSchema: schema}
+	obj, err := sp.JSONTypeFromSchema("Spell", schema, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(expectedObj, obj) {
+		t.Errorf("expected %#v, got %#v", expectedObj, obj)
+		return
+	}
+
+	tests := []struct {
+		Field    JSONField
+		Expected string
+	}{
+		{expectedObj.Fields[0], "Caster *Caster `json:\"caster,omitempty\"`"},
+		{expectedObj.Fields[1], "Name *string `json:\"name\"`"},
+		{JSONField{Name: "tags", Type: JSONNullable{Value: JSONArray{Name: "Tags", Items: JSONString{}}}, Required: true}, "Tags []string `json:\"tags\"`"},
+	}
+	for _, test := range tests {
+		if goField := sp.JSONFieldToGoField(test.Field); goField != test.Expected {
+			t.Errorf("expected %q, got %q", test.Expected, goField)
+		}
+	}
+}
+
+func TestParseInvalidType(t *testing.T) {
+	tests := []string{
+		`{"type": 42}`,
+		`{"type": ["string", 42]}`,
+	}
+	for _, test := range tests {
+		var schema Schema
+		if err := json.Unmarshal([]byte(test), &schema); err == nil {
+			t.Errorf("%s: expected an error", test)
+		}
+	}
+}
+
+func TestParseFormats(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": "object",
+    "definitions": {
+        "price": {
+            "type": "string"
+        }
+    },
+    "properties": {
+        "checksum": {
+            "type": "string",
+            "format": "byte"
+        },
+        "id": {
+            "type": "string",
+            "format": "uuid"
+        },
+        "mana": {
+            "type": "integer",
+            "format": "int32"
+        },
+        "price": {
+            "$ref": "#/definitions/price"
+        },
+        "ratio": {
+            "type": "number",
+            "format": "float"
+        },
+        "since": {
+            "type": "string",
+            "format": "date"
+        },
+        "slug": {
+            "type": "string",
+            "format": "[a-z]+"
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := SchemaParser{
+		RootSchema: schema,
+		GoTypes: map[string]string{
+			"uuid":                "github.com/google/uuid.UUID",
+			"#/definitions/price": "github.com/shopspring/decimal.Decimal",
+		},
+	}
+	obj, err := sp.JSONTypeFromSchema("Spell", schema, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expectedObj := JSONObject{
+		Name: "Spell",
+		Fields: JSONFieldList{
+			{Name: "checksum", Type: JSONString{Format: "byte"}},
+			{Name: "id", Type: JSONGoType{GoType: "github.com/google/uuid.UUID"}},
+			{Name: "mana", Type: JSONInteger{Format: "int32"}},
+			{Name: "price", Type: JSONGoType{ref: "#/definitions/price", GoType: "github.com/shopspring/decimal.Decimal"}},
+			{Name: "ratio", Type: JSONNumber{Format: "float"}},
+			{Name: "since", Type: JSONString{Format: "date"}, Constraints: Constraints{Format: "date"}},
+			{Name: "slug", Type: JSONString{}},
+		},
+	}
+	if !reflect.DeepEqual(expectedObj, obj) {
+		t.Errorf("expected %#v, got %#v", expectedObj, obj)
+		return
+	}
+
+	expectedGoTypes := []string{"[]byte", "uuid.UUID", "int32", "decimal.Decimal", "float32", "string", "string"}
+	for i, f := range expectedObj.Fields {
+		if goType := sp.JSONToGoType(f.Type, false); goType != expectedGoTypes[i] {
+			t.Errorf("%s: expected %q, got %q", f.Name, expectedGoTypes[i], goType)
+		}
+	}
+}
+
+func TestSplitGoType(t *testing.T) {
+	tests := []struct {
+		GoType     string
+		ImportPath string
+		Expected   string
+	}{
+		{"int64", "", "int64"},
+		{"time.Duration", "time", "time.Duration"},
+		{"*math/big.Int", "math/big", "*big.Int"},
+		{"[]github.com/shopspring/decimal.Decimal", "github.com/shopspring/decimal", "[]decimal.Decimal"},
+		{"github.com/jackc/pgx/v5/pgtype.Numeric", "github.com/jackc/pgx/v5/pgtype", "pgtype.Numeric"},
+		{"github.com/gofrs/uuid/v5.UUID", "github.com/gofrs/uuid/v5", "uuid.UUID"},
+		{"gopkg.in/guregu/null.v4.String", "gopkg.in/guregu/null.v4", "null.String"},
+	}
+	for _, test := range tests {
+		importPath, goType := splitGoType(test.GoType)
+		if importPath != test.ImportPath || goType != test.Expected {
+			t.Errorf("%s: expected (%q, %q), got (%q, %q)", test.GoType, test.ImportPath, test.Expected, importPath, goType)
+		}
+	}
+}
+
+func TestParsePropertyOrder(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "type": "string"
+        },
+        "element": {
+            "type": "string"
+        }
+    },
+    "$defs": {
+        "school": {
+            "type": "string"
+        },
+        "aura": {
+            "type": "string"
+        }
+    },
+    "properties": {
+        "name": {
+            "type": "string"
+        },
+        "element": {
+            "$ref": "#/definitions/element"
+        },
+        "cost": {
+            "type": "integer"
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	if names := schema.DefinitionNames(); !reflect.DeepEqual([]string{"spell", "element"}, names) {
+		t.Errorf("expected definitions in document order, got %v", names)
+	}
+	if names := schema.DefsNames(); !reflect.DeepEqual([]string{"school", "aura"}, names) {
+		t.Errorf("expected $defs in document order, got %v", names)
+	}
+	schema.Properties["armor"] = &Schema{Type: SchemaType{"string"}}
+	if names := schema.PropertyNames(); !reflect.DeepEqual([]string{"name", "element", "cost", "armor"}, names) {
+		t.Errorf("expected properties in document order, got %v", names)
+	}
+
+	tests := []struct {
+		SortFields bool
+		Expected   []string
+	}{
+		{false, []string{"name", "element", "cost", "armor"}},
+		{true, []string{"armor", "cost", "element", "name"}},
+	}
+	for _, test := range tests {
+		sp := SchemaParser{RootSchema: schema, SortFields: test.SortFields}
+		jt, err := sp.JSONTypeFromSchema("Spell", schema, "")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var names []string
+		for _, f := range jt.(JSONObject).Fields {
+			names = append(names, f.Name)
+		}
+		if !reflect.DeepEqual(test.Expected, names) {
+			t.Errorf("SortFields=%v: expected fields %v, got %v", test.SortFields, test.Expected, names)
+		}
 	}
 }
 
@@ -230,7 +720,7 @@ func TestParseJSONStructWithMixedRef(t *testing.T) {
 		},
 	}
 	sort.Sort(expectedObj.Fields)
-	sp := SchemaParser{RootSchema: schema}
+	sp := SchemaParser{RootSchema: schema, SortFields: true}
 	obj, err := sp.JSONTypeFromSchema("Spell", spellSchema, spellSchema.Ref)
 	if err != nil {
 		t.Error(err)
@@ -346,7 +836,7 @@ func TestParseSchemaWithRoutesOneResource(t *testing.T) {
 	}
 	sort.Sort(expectedRoutes)
 
-	sp := SchemaParser{RootSchema: schema}
+	sp := SchemaParser{RootSchema: schema, SortFields: true}
 	routes, err := sp.ParseRoutes()
 	if err != nil {
 		t.Error(err)
@@ -427,7 +917,7 @@ func TestParseSchemaByResource(t *testing.T) {
 			Path: "/armors/{armor-id}",
 			Name: "armors.one",
 			RouteParams: []RouteParam{
-				{Name: "armor-id", Varname: "armorId", Type: JSONString{ref: "#/definitions/armor/definitions/id"}},
+				{Name: "armor-id", Varname: "armorID", Type: JSONString{ref: "#/definitions/armor/definitions/id"}},
 			},
 			MethodRouteIOMap: MethodRouteIOMap{
 				"GET": RouteIOAndLink{
@@ -542,7 +1032,7 @@ func TestParseSchemaByResource(t *testing.T) {
 			Path: "/weapons/{weapon-id}",
 			Name: "weapons.one",
 			RouteParams: []RouteParam{
-				{Name: "weapon-id", Varname: "weaponId", Type: JSONString{ref: "#/definitions/weapon/definitions/id"}},
+				{Name: "weapon-id", Varname: "weaponID", Type: JSONString{ref: "#/definitions/weapon/definitions/id"}},
 			},
 			MethodRouteIOMap: MethodRouteIOMap{
 				"GET": RouteIOAndLink{
@@ -572,7 +1062,7 @@ func TestParseSchemaByResource(t *testing.T) {
 	}
 	sort.Sort(expectedResourceRoutes)
 
-	sp := SchemaParser{RootSchema: schema}
+	sp := SchemaParser{RootSchema: schema, SortFields: true}
 	routes, err := sp.ParseRoutes()
 	if err != nil {
 		t.Error(err)
@@ -623,7 +1113,7 @@ func TestMixedRouteParams(t *testing.T) {
 			Path: "/locations/{location-id}",
 			Name: "locations.one",
 			RouteParams: []RouteParam{
-				{Name: "location-id", Varname: "locationId", Type: JSONInteger{ref: "#/definitions/location/definitions/id"}},
+				{Name: "location-id", Varname: "locationID", Type: JSONInteger{ref: "#/definitions/location/definitions/id"}},
 			},
 			Method: "GET",
 			RouteIO: RouteIO{
@@ -650,7 +1140,7 @@ func TestMixedRouteParams(t *testing.T) {
 			Path: "/weapons/{weapon-id}",
 			Name: "weapons.one",
 			RouteParams: []RouteParam{
-				{Name: "weapon-id", Varname: "weaponId", Type: JSONString{ref: "#/definitions/weapon/definitions/id"}},
+				{Name: "weapon-id", Varname: "weaponID", Type: JSONString{ref: "#/definitions/weapon/definitions/id"}},
 			},
 			Method: "GET",
 			RouteIO: RouteIO{
@@ -703,7 +1193,7 @@ func TestMixedRouteParams(t *testing.T) {
 	}
 	sort.Sort(expectedRoutes)
 
-	sp := SchemaParser{RootSchema: schema}
+	sp := SchemaParser{RootSchema: schema, SortFields: true}
 	routes, err := sp.ParseRoutes()
 	if err != nil {
 		t.Error(err)
@@ -749,6 +1239,7 @@ func TestVarsFromHRef(t *testing.T) {
 	}{
 		{href: "/spells/{(#/definitions/spell/definitions/name)}", vars: []string{"#/definitions/spell/definitions/name"}, valid: true},
 		{href: "/documents/{(#/definitions/document/definitions/id)}/pages/{(#/definitions/page/definitions/id)}", vars: []string{"#/definitions/document/definitions/id", "#/definitions/page/definitions/id"}, valid: true},
+		{href: "/spells/{(#/definitions/spell/definitions/name)}{?page,per_page}", vars: []string{"#/definitions/spell/definitions/name"}, valid: true},
 	}
 
 	for _, test := range tests {
@@ -776,6 +1267,10 @@ func TestHRef2name(t *testing.T) {
 		{href: "/spells/{(#/definitions/spell/definitions/name)}", name: "spells.one"},
 		{href: "/spells/{(#/definitions/id)}", name: "spells.one"},
 		{href: "/spells/{id}", name: "spells.one"},
+		{href: "/spells{?page,per_page}", name: "spells"},
+		{href: "/spells/{id}{?page}{&per_page}", name: "spells.one"},
+		{href: "/", name: "index"},
+		{href: "{?q}", name: "index"},
 	}
 
 	for _, test := range tests {
@@ -791,6 +1286,387 @@ func TestHRef2name(t *testing.T) {
 	}
 }
 
+func TestQueryVarsFromHRef(t *testing.T) {
+	tests := []struct {
+		href string
+		vars []string
+	}{
+		{href: "/spells", vars: nil},
+		{href: "/spells/{(#/definitions/spell/definitions/name)}", vars: nil},
+		{href: "/spells{?page,per_page}", vars: []string{"page", "per_page"}},
+		{href: "/spells{?page}{&tags*}", vars: []string{"page", "tags"}},
+		{href: "/spells{?(#/definitions/spell/definitions/order),page}", vars: []string{"#/definitions/spell/definitions/order", "page"}},
+	}
+
+	for _, test := range tests {
+		vars, err := queryVarsFromHRef(test.href)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !reflect.DeepEqual(test.vars, vars) {
+			t.Errorf("%s: expected %#v, got %#v", test.href, test.vars, vars)
+		}
+	}
+}
+
+func TestParseQueryType(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "definitions": {
+                "name": {"type": "string"},
+                "order": {"type": "string", "enum": ["asc", "desc"]}
+            },
+            "properties": {
+                "name": {"$ref": "#/definitions/spell/definitions/name"},
+                "level": {"type": "integer"}
+            },
+            "links": [
+                {
+                    "href": "/spells{?(#/definitions/spell/definitions/order),level,per_page}",
+                    "method": "GET",
+                    "rel": "instances",
+                    "schema": {
+                        "type": "object",
+                        "properties": {
+                            "page": {"type": "integer"},
+                            "tags": {"type": "array", "items": {"type": "string"}},
+                            "q": {"type": "string"}
+                        },
+                        "required": ["q"]
+                    }
+                },
+                {
+                    "href": "/spells{?dry_run}",
+                    "method": "POST",
+                    "rel": "create",
+                    "schema": {"$ref": "#/definitions/spell"}
+                }
+            ]
+        }
+    },
+    "properties": {
+        "spell": {"$ref": "#/definitions/spell"}
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(routes) != 2 {
+		t.Errorf("expected 2 routes, got %#v", routes)
+		return
+	}
+
+	expectedQueryTypes := map[string]JSONType{
+		"GET": JSONObject{
+			Name: "InstancesSpellQuery",
+			Fields: JSONFieldList{
+				{Name: "page", Type: JSONInteger{}},
+				{Name: "tags", Type: JSONArray{Name: "Tags", Items: JSONString{}}},
+				{Name: "q", Type: JSONString{}, Required: true},
+				{Name: "order", Type: JSONEnum{Name: "SpellOrder", ref: "#/definitions/spell/definitions/order", Values: []string{"asc", "desc"}}},
+				{Name: "level", Type: JSONInteger{ref: "level"}},
+				{Name: "per_page", Type: JSONString{}},
+			},
+		},
+		"POST": JSONObject{
+			Name: "CreateSpellQuery",
+			Fields: JSONFieldList{
+				{Name: "dry_run", Type: JSONString{}},
+			},
+		},
+	}
+	for _, route := range routes {
+		if route.Path != "/spells" {
+			t.Errorf("expected path /spells, got %s", route.Path)
+		}
+		if !reflect.DeepEqual(expectedQueryTypes[route.Method], route.QueryType) {
+			t.Errorf("%s: expected query type %#v, got %#v", route.Method, expectedQueryTypes[route.Method], route.QueryType)
+		}
+		switch route.Method {
+		case "GET":
+			// The schema of a GET link describes the query string, not the request body.
+			if route.InType != nil {
+				t.Errorf("GET: expected no input type, got %#v", route.InType)
+			}
+		case "POST":
+			if route.InType == nil {
+				t.Error("POST: expected an input type")
+			}
+		}
+	}
+}
+
+func TestParseInvalidQueryType(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "properties": {
+                "name": {"type": "string"}
+            },
+            "links": [
+                {
+                    "href": "/spells",
+                    "method": "GET",
+                    "rel": "instances",
+                    "schema": {
+                        "type": "object",
+                        "properties": {
+                            "filter": {
+                                "type": "object",
+                                "properties": {"name": {"type": "string"}}
+                            }
+                        }
+                    }
+                }
+            ]
+        }
+    },
+    "properties": {
+        "spell": {"$ref": "#/definitions/spell"}
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	_, err := sp.ParseRoutes()
+	if e, ok := err.(InvalidSchemaError); !ok || !strings.Contains(e.Msg, `query parameter "filter"`) {
+		t.Errorf("expected an InvalidSchemaError about the filter query parameter, got %#v", err)
+	}
+}
+
+func TestParseReadOnlyInputType(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": "object",
+    "definitions": {
+        "server": {
+            "definitions": {
+                "id": {"type": "string", "readOnly": true},
+                "name": {"type": "string"}
+            },
+            "properties": {
+                "id": {"$ref": "#/definitions/server/definitions/id"},
+                "name": {"$ref": "#/definitions/server/definitions/name"},
+                "created_at": {"type": "string", "readOnly": true}
+            },
+            "required": ["id", "name"],
+            "links": [
+                {
+                    "href": "/servers",
+                    "method": "POST",
+                    "rel": "create",
+                    "schema": {"$ref": "#/definitions/server"},
+                    "targetSchema": {"$ref": "#/definitions/server"}
+                },
+                {
+                    "href": "/servers",
+                    "method": "PUT",
+                    "rel": "replace",
+                    "schema": {"type": "array", "items": {"$ref": "#/definitions/server"}}
+                }
+            ]
+        }
+    },
+    "properties": {
+        "server": {"$ref": "#/definitions/server"}
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	server := JSONObject{
+		Name: "Server",
+		ref:  "#/definitions/server",
+		Fields: JSONFieldList{
+			{Name: "id", Type: JSONString{ref: "#/definitions/server/definitions/id"}, Required: true, ReadOnly: true},
+			{Name: "name", Type: JSONString{ref: "#/definitions/server/definitions/name"}, Required: true},
+			{Name: "created_at", Type: JSONString{}, ReadOnly: true},
+		},
+	}
+	serverInput := JSONObject{
+		Name: "ServerInput",
+		Fields: JSONFieldList{
+			{Name: "name", Type: JSONString{ref: "#/definitions/server/definitions/name"}, Required: true},
+		},
+	}
+	expectedIO := map[string]RouteIO{
+		"POST": {InType: serverInput, OutType: server},
+		"PUT":  {InType: JSONArray{Name: "ReplaceServerIn", Items: serverInput}},
+	}
+	for _, route := range routes {
+		if !reflect.DeepEqual(expectedIO[route.Method], route.RouteIO) {
+			t.Errorf("%s: expected %#v, got %#v", route.Method, expectedIO[route.Method], route.RouteIO)
+		}
+	}
+}
+
+func TestParseReadOnlyInputTypeWithAllOf(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": "object",
+    "definitions": {
+        "base": {
+            "required": ["id"],
+            "properties": {
+                "id": {"type": "string", "format": "uuid", "readOnly": true},
+                "created_at": {"type": "string", "format": "date-time", "readOnly": true}
+            }
+        },
+        "named": {
+            "required": ["id", "name"],
+            "properties": {
+                "id": {"type": "string", "format": "uuid", "readOnly": true},
+                "name": {"type": "string", "minLength": 1}
+            }
+        },
+        "user": {
+            "allOf": [{"$ref": "#/definitions/named"}]
+        },
+        "server": {
+            "allOf": [
+                {"$ref": "#/definitions/base"},
+                {
+                    "properties": {
+                        "owner": {"$ref": "#/definitions/user"},
+                        "admins": {"type": "array", "items": {"$ref": "#/definitions/user"}},
+                        "labels": {"type": "object", "additionalProperties": {"$ref": "#/definitions/named"}}
+                    }
+                }
+            ],
+            "links": [
+                {
+                    "href": "/servers",
+                    "method": "POST",
+                    "rel": "create",
+                    "schema": {"$ref": "#/definitions/server"},
+                    "targetSchema": {"$ref": "#/definitions/server"}
+                }
+            ]
+        }
+    },
+    "properties": {
+        "server": {"$ref": "#/definitions/server"}
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(routes) != 1 {
+		t.Errorf("expected 1 route, got %d", len(routes))
+		return
+	}
+	// The base has only readOnly properties: it's not embedded in the input variant.
+	tests := []struct {
+		Type     JSONType
+		Expected string
+	}{
+		{routes[0].OutType, "struct {\nBase\nOwner *User `json:\"owner,omitempty\"`\nAdmins []User `json:\"admins,omitempty\"`\nLabels map[string]Named `json:\"labels,omitempty\"`\n}"},
+		{routes[0].InType, "struct {\nOwner *UserInput `json:\"owner,omitempty\"`\nAdmins []UserInput `json:\"admins,omitempty\"`\nLabels map[string]NamedInput `json:\"labels,omitempty\"`\n}"},
+	}
+	for _, test := range tests {
+		if goType := sp.JSONToGoType(test.Type, true); goType != test.Expected {
+			t.Errorf("expected %s, got %s", test.Expected, goType)
+		}
+	}
+	if name := sp.JSONToGoType(routes[0].InType, false); name != "ServerInput" {
+		t.Errorf("expected ServerInput, got %s", name)
+	}
+	userInput := routes[0].InType.(JSONObject).Fields[0].Type.(JSONObject)
+	if goType := sp.JSONToGoType(userInput, true); goType != "struct {\nNamedInput\n}" {
+		t.Errorf("expected UserInput to embed NamedInput, got %s", goType)
+	}
+}
+
+func TestParseReadOnlyInputTypeWithUnion(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "cat": {
+            "required": ["kind"],
+            "properties": {
+                "id": {"type": "string", "readOnly": true},
+                "kind": {"type": "string", "enum": ["cat"]},
+                "lives": {"type": "integer", "minimum": 1}
+            }
+        },
+        "dog": {
+            "required": ["kind"],
+            "properties": {
+                "kind": {"type": "string", "enum": ["dog"]},
+                "breed": {"type": "string"}
+            }
+        },
+        "pet": {
+            "x-discriminator": "kind",
+            "oneOf": [{"$ref": "#/definitions/cat"}, {"$ref": "#/definitions/dog"}],
+            "links": [
+                {"href": "/pets", "method": "POST", "rel": "create", "schema": {"$ref": "#/definitions/pet"}, "targetSchema": {"$ref": "#/definitions/pet"}}
+            ]
+        }
+    },
+    "properties": {
+        "pet": {"$ref": "#/definitions/pet"}
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(routes) != 1 {
+		t.Errorf("expected 1 route, got %d", len(routes))
+		return
+	}
+	// The variants with readOnly properties have their input variant.
+	tests := []struct {
+		Type     JSONType
+		Name     string
+		Expected string
+	}{
+		{routes[0].OutType, "Pet", "struct {\nCat *Cat\nDog *Dog\n}"},
+		{routes[0].InType, "PetInput", "struct {\nCatInput *CatInput\nDog *Dog\n}"},
+	}
+	for _, test := range tests {
+		if name := sp.JSONToGoType(test.Type, false); name != test.Name {
+			t.Errorf("expected %s, got %s", test.Name, name)
+		}
+		if goType := sp.JSONToGoType(test.Type, true); goType != test.Expected {
+			t.Errorf("expected %s, got %s", test.Expected, goType)
+		}
+	}
+	catInput := routes[0].InType.(JSONUnion).Variants[0]
+	if goType := sp.JSONToGoType(catInput, true); goType != "struct {\nKind CatKind `json:\"kind\"`\nLives *int `json:\"lives,omitempty\"`\n}" {
+		t.Errorf("expected CatInput without id, got %s", goType)
+	}
+}
+
 func TestParseKrakenSchema(t *testing.T) {
 	schema := getSchema(t, "testdata/kraken.json")
 	if t.Failed() {
@@ -1224,8 +2100,9 @@ func TestParseKrakenSchema(t *testing.T) {
 							Fields: JSONFieldList{
 								JSONField{
 									Name: "fs_params",
-									Type: JSONObject{
-										Name: "FsParams",
+									Type: JSONMap{
+										Values:     JSONString{},
+										KeyPattern: ".+",
 									},
 								},
 								JSONField{
@@ -1318,7 +2195,7 @@ func TestParseKrakenSchema(t *testing.T) {
 				},
 				RouteParam{
 					Name:    "mount-id",
-					Varname: "mountId",
+					Varname: "mountID",
 					Type:    JSONString{ref: "#/definitions/mount/definitions/id"},
 				},
 			},
@@ -1390,7 +2267,7 @@ func TestParseKrakenSchema(t *testing.T) {
 	}
 	sort.Sort(expectedResourceRoutes)
 
-	sp := SchemaParser{RootSchema: schema}
+	sp := SchemaParser{RootSchema: schema, SortFields: true}
 	routes, err := sp.ParseRoutes()
 	if err != nil {
 		t.Error(err)
@@ -1406,7 +2283,7 @@ func TestParseSchemaWithNonJSONEndpoints(t *testing.T) {
 	if t.Failed() {
 		return
 	}
-	sp := SchemaParser{RootSchema: schema}
+	sp := SchemaParser{RootSchema: schema, SortFields: true}
 	routes, err := sp.ParseRoutes()
 	if err != nil {
 		t.Error(err)
@@ -1430,11 +2307,12 @@ func TestParseSchemaWithNonJSONEndpoints(t *testing.T) {
 			RouteIO: RouteIO{
 				InputIsNotJSON: true,
 				OutType: JSONObject{
-					Name: "File",
-					ref:  "#/definitions/file",
+					Name:        "File",
+					ref:         "#/definitions/file",
+					Description: "A file is a file resource.",
 					Fields: JSONFieldList{ // .Name natural sort
 						{Name: "content_type", Type: JSONString{ref: "#/definitions/file/definitions/contenttype"}},
-						{Name: "creation_date", Type: JSONDateTime{ref: "#/definitions/file/definitions/creationdate"}},
+						{Name: "creation_date", Type: JSONDateTime{ref: "#/definitions/file/definitions/creationdate"}, Description: "the datetime on which the file was added"},
 						{Name: "id", Type: JSONString{ref: "#/definitions/file/definitions/id"}},
 					},
 				},
@@ -1458,11 +2336,12 @@ func TestParseSchemaWithNonJSONEndpoints(t *testing.T) {
 					Name: "ListFileOut",
 					ref:  "",
 					Items: JSONObject{
-						Name: "File",
-						ref:  "#/definitions/file",
+						Name:        "File",
+						ref:         "#/definitions/file",
+						Description: "A file is a file resource.",
 						Fields: JSONFieldList{ // .Name natural sort
 							{Name: "content_type", Type: JSONString{ref: "#/definitions/file/definitions/contenttype"}},
-							{Name: "creation_date", Type: JSONDateTime{ref: "#/definitions/file/definitions/creationdate"}},
+							{Name: "creation_date", Type: JSONDateTime{ref: "#/definitions/file/definitions/creationdate"}, Description: "the datetime on which the file was added"},
 							{Name: "id", Type: JSONString{ref: "#/definitions/file/definitions/id"}},
 						},
 					},
@@ -1473,7 +2352,7 @@ func TestParseSchemaWithNonJSONEndpoints(t *testing.T) {
 			Path: "/files/{file-id}",
 			Name: "files.one",
 			RouteParams: []RouteParam{
-				{Name: "file-id", Varname: "fileId", Type: JSONString{ref: "#/definitions/file/definitions/id"}},
+				{Name: "file-id", Varname: "fileID", Type: JSONString{ref: "#/definitions/file/definitions/id"}},
 			},
 			Method: "GET",
 			Link: Link{
@@ -1495,3 +2374,118 @@ func TestParseSchemaWithNonJSONEndpoints(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestSchemaErrorLocation(t *testing.T) {
+	tests := []struct {
+		Desc     string
+		Schema   string
+		Expected string
+	}{
+		{
+			"invalid pattern",
+			"{\n  \"type\": \"object\",\n  \"properties\": {\n    \"spell\": {\n      \"type\": \"object\",\n      \"properties\": {\"name\": {\"type\": \"string\", \"pattern\": \"([\"}},\n      \"links\": [{\"href\": \"/spells\", \"method\": \"POST\", \"rel\": \"create\", \"schema\": {\"$ref\": \"#/properties/spell\"}}]\n    }\n  }\n}\n",
+			"api.json:6:49: #/properties/spell/properties/name/pattern: invalid pattern",
+		},
+		{
+			"invalid $ref",
+			"{\n  \"type\": \"object\",\n  \"properties\": {\n    \"spell\": {\n      \"type\": \"object\",\n      \"links\": [{\"href\": \"/spells\", \"method\": \"POST\", \"rel\": \"create\", \"schema\": {\"$ref\": \"#/definitions/nope\"}}]\n    }\n  }\n}\n",
+			`api.json:6:83: #/properties/spell/links/0/schema/$ref: invalid $ref "#/definitions/nope"`,
+		},
+		{
+			"duplicate rel",
+			"{\n  \"type\": \"object\",\n  \"properties\": {\n    \"spell\": {\n      \"links\": [\n        {\"href\": \"/spells\", \"method\": \"GET\", \"rel\": \"self\"},\n        {\"href\": \"/spell\", \"method\": \"GET\", \"rel\": \"self\"}\n      ]\n    }\n  }\n}\n",
+			"api.json:7:45: #/properties/spell/links/1/rel: duplicate link",
+		},
+		{
+			"invalid href",
+			"{\n  \"type\": \"object\",\n  \"properties\": {\n    \"spell\": {\n      \"links\": [{\"href\": \"/spells/{(#/definitions/name)}\", \"method\": \"GET\", \"rel\": \"self\"}]\n    }\n  }\n}\n",
+			`api.json:5:18: #/properties/spell/links/0/href: invalid $ref "#/definitions/name"`,
+		},
+	}
+	for _, test := range tests {
+		schema, err := parseSchema(strings.NewReader(test.Schema), FormatJSON, "api.json")
+		if err != nil {
+			t.Errorf("%s: %v", test.Desc, err)
+			continue
+		}
+		sp := &SchemaParser{RootSchema: schema}
+		_, err = sp.ParseRoutes()
+		if err == nil {
+			t.Errorf("%s: expected an error", test.Desc)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), test.Expected) {
+			t.Errorf("%s: expected an error starting with %q, got %q", test.Desc, test.Expected, err)
+		}
+	}
+}
+
+func TestParseRoutesFromDefinitions(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "type": "object",
+            "properties": {
+                "name": {"type": "string"}
+            },
+            "links": [
+                {"href": "/spells", "method": "GET", "rel": "list", "targetSchema": {"type": "array", "items": {"$ref": "#/definitions/spell"}}}
+            ]
+        },
+        "school": {
+            "type": "object",
+            "links": [
+                {"href": "/schools", "method": "POST", "rel": "create", "schema": {"type": "object", "properties": {"name": {"type": "string"}}}}
+            ],
+            "definitions": {
+                "teacher": {
+                    "links": [
+                        {"href": "/teachers", "method": "GET", "rel": "list"}
+                    ]
+                }
+            }
+        }
+    },
+    "links": [
+        {"href": "/", "method": "GET", "rel": "index", "targetSchema": {"type": "object", "properties": {"version": {"type": "string"}}}},
+        {"href": "/status", "method": "GET", "rel": "self", "targetSchema": {"type": "object", "properties": {"healthy": {"type": "boolean"}}}}
+    ],
+    "properties": {
+        "spell": {"$ref": "#/definitions/spell"},
+        "favoriteSpell": {"$ref": "#/definitions/spell"}
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := SchemaParser{RootSchema: schema}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// The spell's routes are parsed once, named after the first property referencing it.
+	// The types of the root links are named after their route.
+	expected := []string{
+		"GET / IndexOut",
+		"POST /schools CreateSchoolIn",
+		"GET /spells ListSpellOut",
+		"GET /status SelfStatusOut",
+		"GET /teachers ",
+	}
+	var actual []string
+	for _, route := range routes {
+		var typeName string
+		for _, typ := range []JSONType{route.InType, route.OutType} {
+			if jtn, ok := typ.(JSONTypeNamer); ok {
+				typeName = jtn.TypeName()
+			}
+		}
+		actual = append(actual, fmt.Sprintf("%s %s %s", route.Method, route.Path, typeName))
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected routes %q, got %q", expected, actual)
+	}
+}
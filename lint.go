@@ -0,0 +1,294 @@
+package dispel
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// LintIssue represents a feature of a schema which dispel ignores, or which is likely a mistake.
+type LintIssue struct {
+	// Pointer is the JSON pointer of the node of the schema document where the issue occurs,
+	// e.g #/definitions/spell/links/0.
+	Pointer string
+	// Location is the position of the node in the source document, if known.
+	Location Location
+	Msg      string
+}
+
+// String returns the message of the issue, prefixed with its location and its pointer.
+func (i LintIssue) String() string {
+	return locatedMsg(i.Pointer, i.Location, i.Msg)
+}
+
+// ignoredKeywords are the keywords decoded in a Schema which have no effect on the generated code.
+var ignoredKeywords = []string{"minProperties", "maxProperties", "dependencies", "additionalItems", "not"}
+
+// Lint walks the root schema document and returns the issues it finds, in the order of the document:
+//   - the keywords dispel ignores, and the unknown ones
+//   - the $refs which can't be resolved, and the $ref cycles
+//   - the oneOf with an integer and a number variant, which reject integers
+//   - the required properties whose default value is never applied
+//   - the links without rel, or whose method isn't one dispel generates handlers for
+//   - the href variables matching no property nor definition
+//   - the request and response bodies which aren't generated, because their media type isn't JSON
+//   - the definitions which are never referenced, and have no links
+func (sp *SchemaParser) Lint() []LintIssue {
+	l := &linter{sp: sp, used: make(map[*Schema]bool), cycles: make(map[string]bool)}
+	l.lintSchema(sp.RootSchema, "#")
+	l.lintDefinitions(sp.RootSchema, "#")
+	return l.issues
+}
+
+// linter accumulates the issues found in a schema document.
+type linter struct {
+	sp     *SchemaParser
+	issues []LintIssue
+	// used holds the schemas referenced by a $ref or an href variable.
+	used map[*Schema]bool
+	// cycles holds the $ref cycles reported.
+	cycles map[string]bool
+}
+
+func (l *linter) report(pointer string, format string, v ...interface{}) {
+	l.issues = append(l.issues, LintIssue{
+		Pointer:  pointer,
+		Location: l.sp.RootSchema.location(strings.TrimPrefix(pointer, "#")),
+		Msg:      fmt.Sprintf(format, v...),
+	})
+}
+
+func (l *linter) lintSchema(s *Schema, pointer string) {
+	sv := reflect.ValueOf(s).Elem()
+	for _, keyword := range ignoredKeywords {
+		if fv := sv.FieldByName(schemaKeywordField(keyword)); !isZeroValue(fv) {
+			l.report(pointer+"/"+keyword, "%s is not supported, it's ignored", keyword)
+		}
+	}
+	for _, keyword := range s.unknownKeywords {
+		// Extensions and comments are legit keywords.
+		if strings.HasPrefix(keyword, "x-") || keyword == "$comment" {
+			continue
+		}
+		l.report(pointer+"/"+pointerToken(keyword), "unknown keyword %s is ignored", keyword)
+	}
+	if s.Ref != "" {
+		// The issue is located already, the error doesn't need to be.
+		if target, err := l.sp.resolveSchemaRef(s.Ref, s); err != nil {
+			l.report(pointer+"/$ref", "%v", err)
+		} else {
+			l.used[target] = true
+			l.lintRefCycle(s)
+		}
+	}
+
+	for _, name := range s.DefinitionNames() {
+		l.lintSchema(s.Definitions[name], pointer+"/definitions/"+pointerToken(name))
+	}
+	for _, name := range s.DefsNames() {
+		l.lintSchema(s.Defs[name], pointer+"/$defs/"+pointerToken(name))
+	}
+	required := make(map[string]bool)
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	for _, name := range s.PropertyNames() {
+		propertyPointer := pointer + "/properties/" + pointerToken(name)
+		l.lintSchema(s.Properties[name], propertyPointer)
+		if required[name] && l.hasIgnoredDefault(s.Properties[name]) {
+			l.report(propertyPointer, "property %s is required, its default value is never applied", name)
+		}
+	}
+	for _, pattern := range orderedKeys(s.PatternProperties, nil) {
+		l.lintSchema(s.PatternProperties[pattern], pointer+"/patternProperties/"+pointerToken(pattern))
+	}
+	if s.Items != nil {
+		l.lintSchema(s.Items, pointer+"/items")
+	}
+	for _, composition := range []struct {
+		keyword string
+		schemas []Schema
+	}{{"oneOf", s.OneOf}, {"anyOf", s.AnyOf}, {"allOf", s.AllOf}} {
+		for i := range composition.schemas {
+			l.lintSchema(&composition.schemas[i], fmt.Sprintf("%s/%s/%d", pointer, composition.keyword, i))
+		}
+	}
+	if l.hasIntegerAndNumber(s.OneOf) {
+		l.report(pointer+"/oneOf", "oneOf has an integer and a number variant: integers match both, they're rejected")
+	}
+	for i := range s.Links {
+		l.lintLink(s.Links[i], s, fmt.Sprintf("%s/links/%d", pointer, i))
+	}
+}
+
+// lintRefCycle reports the $ref cycle that resolving s runs into, if any.
+// A cycle is reported once, at the $ref of its schema with the smallest ref, like ParseRoutes does.
+func (l *linter) lintRefCycle(s *Schema) {
+	_, err := l.sp.ResolveSchema(s)
+	e, ok := err.(InvalidSchemaRefError)
+	if !ok || !strings.HasPrefix(e.Msg, "$ref cycle") || l.cycles[e.Pointer] {
+		// The other invalid $refs are reported by the schemas holding them.
+		return
+	}
+	l.cycles[e.Pointer] = true
+	l.report(e.Pointer, "%v", InvalidSchemaRefError{Ref: e.Ref, Msg: e.Msg})
+}
+
+// hasIgnoredDefault returns true if the property s has a default value which isn't applied if s is required:
+// the Go type of a required string, number, integer or boolean can't be nil, its absence can't be told
+// apart from its zero value.
+func (l *linter) hasIgnoredDefault(s *Schema) bool {
+	// The issue of an invalid $ref is reported already.
+	resolved, err := l.sp.ResolveSchema(s)
+	if err != nil || (s.Default == nil && resolved.Default == nil) {
+		return false
+	}
+	switch resolved.Type.String() {
+	case "string":
+		return resolved.Format != "byte"
+	case "number", "integer", "boolean":
+		return true
+	}
+	return false
+}
+
+// hasIntegerAndNumber returns true if one of the variants is an integer, and another one a number.
+func (l *linter) hasIntegerAndNumber(variants []Schema) bool {
+	types := make(map[string]bool)
+	for i := range variants {
+		// The issue of an invalid $ref is reported already.
+		if variant, err := l.sp.ResolveSchema(&variants[i]); err == nil {
+			types[variant.Type.String()] = true
+		}
+	}
+	return types["integer"] && types["number"]
+}
+
+func (l *linter) lintLink(link Link, s *Schema, pointer string) {
+	for _, sub := range []struct {
+		keyword string
+		schema  *Schema
+	}{
+		{"schema", link.Schema},
+		{"targetSchema", link.TargetSchema},
+		{"hrefSchema", link.HRefSchema},
+		{"submissionSchema", link.SubmissionSchema},
+	} {
+		if sub.schema != nil {
+			l.lintSchema(sub.schema, pointer+"/"+sub.keyword)
+		}
+	}
+
+	link.ApplyDefaults()
+	if link.Rel == "" {
+		l.report(pointer, "link has no rel")
+	}
+	if link.Method == "" {
+		l.report(pointer, "link has no method")
+	} else if !isKnownMethod(link.Method) {
+		l.report(pointer+"/method", "method %s is not one of %s, no handler is generated for it", link.Method, strings.Join(methodsOrder, ", "))
+	}
+
+	vars, err := varsFromHRef(link.HRef)
+	if err != nil {
+		l.report(pointer+"/href", "%v", err)
+		return
+	}
+	for _, v := range vars {
+		target, _, err := l.sp.hrefVarSchema(v, &link, s)
+		if err != nil {
+			l.report(pointer+"/href", "variable %s matches no property nor definition", v)
+			continue
+		}
+		l.used[target] = true
+	}
+	queryVars, err := queryVarsFromHRef(link.HRef)
+	if err != nil {
+		l.report(pointer+"/href", "%v", err)
+		return
+	}
+	for _, v := range queryVars {
+		if link.Schema != nil && link.SchemaDescribesQuery() {
+			if querySchema, err := l.sp.ResolveSchema(link.Schema); err == nil && querySchema.Properties[v] != nil {
+				continue
+			}
+		}
+		hrefProperty, err := l.sp.hrefSchemaProperty(&link, v)
+		if err != nil {
+			l.report(pointer+"/hrefSchema", "%v", err)
+			continue
+		}
+		if _, ok := s.Properties[v]; !ok && hrefProperty == nil && !strings.Contains(v, "#") {
+			l.report(pointer+"/href", "query variable %s matches no property nor definition, it's decoded as a string", v)
+			continue
+		}
+		target, _, err := l.sp.hrefVarSchema(v, &link, s)
+		if err != nil {
+			l.report(pointer+"/href", "query variable %s matches no property nor definition", v)
+			continue
+		}
+		l.used[target] = true
+	}
+
+	if link.Schema != nil && !link.ReceivesJSON() && !link.SchemaDescribesQuery() {
+		l.report(pointer+"/encType", "the request body of media type %s is not JSON, its schema is ignored", link.EncType)
+	}
+	if link.TargetSchema != nil && !link.SendsJSON() {
+		l.report(pointer+"/mediaType", "the response body of media type %s is not JSON, its schema is ignored", link.MediaType)
+	}
+}
+
+// lintDefinitions reports the definitions of s, and the ones nested in them, which are never referenced.
+func (l *linter) lintDefinitions(s *Schema, pointer string) {
+	for _, defs := range []struct {
+		keyword string
+		schemas map[string]*Schema
+		names   []string
+	}{
+		{"definitions", s.Definitions, s.DefinitionNames()},
+		{"$defs", s.Defs, s.DefsNames()},
+	} {
+		for _, name := range defs.names {
+			def := defs.schemas[name]
+			defPointer := pointer + "/" + defs.keyword + "/" + pointerToken(name)
+			// The links of a definition are routes, it's used.
+			if len(def.Links) == 0 && !l.isUsed(def) {
+				l.report(defPointer, "definition %s is never referenced", name)
+				continue
+			}
+			l.lintDefinitions(def, defPointer)
+		}
+	}
+}
+
+// isUsed returns true if s, or a schema nested in s, is referenced.
+func (l *linter) isUsed(s *Schema) bool {
+	if l.used[s] {
+		return true
+	}
+	for _, sub := range s.subschemas() {
+		if l.isUsed(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// isKnownMethod returns true if method is one of the HTTP methods dispel generates handlers for.
+func isKnownMethod(method string) bool {
+	for _, m := range methodsOrder {
+		if strings.ToUpper(method) == m {
+			return true
+		}
+	}
+	return false
+}
+
+// isZeroValue returns true if v holds the zero value of its type.
+func isZeroValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.Interface() == reflect.Zero(v.Type()).Interface()
+}
@@ -0,0 +1,136 @@
+package dispel
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "definitions": {
+        "spell": {
+            "type": "object",
+            "minProperties": 1,
+            "frobnicate": true,
+            "x-go-name": "Spell",
+            "definitions": {
+                "name": {"type": "string"},
+                "unused~/x": {"type": "string"}
+            },
+            "properties": {
+                "name": {"$ref": "#/definitions/spell/definitions/name"},
+                "school": {"$ref": "#/definitions/school"}
+            },
+            "links": [
+                {"href": "/spells/{(#/definitions/spell/definitions/name)}", "method": "GET", "targetSchema": {"$ref": "#/definitions/spell"}},
+                {"href": "/spells/{(#/definitions/missing)}{?level}", "method": "FETCH", "rel": "self"},
+                {"href": "/spells", "method": "POST", "rel": "create", "encType": "multipart/form-data", "schema": {"$ref": "#/definitions/spell"}, "mediaType": "text/plain", "targetSchema": {"type": "string"}},
+                {"href": "/spells{?page}", "method": "GET", "rel": "instances", "schema": {"type": "object", "properties": {"page": {"type": "integer"}}}}
+            ]
+        },
+        "orphan": {"type": "string"},
+        "price": {"oneOf": [{"type": "integer"}, {"type": "number"}]},
+        "familiar": {
+            "type": "object",
+            "links": [
+                {"href": "/familiars", "method": "GET", "rel": "list"}
+            ]
+        }
+    },
+    "properties": {
+        "spell": {"$ref": "#/definitions/spell"},
+        "price": {"$ref": "#/definitions/price"}
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	expected := []string{
+		"#/definitions/spell/minProperties: minProperties is not supported, it's ignored",
+		"#/definitions/spell/frobnicate: unknown keyword frobnicate is ignored",
+		`#/definitions/spell/properties/school/$ref: invalid $ref "#/definitions/school": invalid ref`,
+		"#/definitions/spell/links/0: link has no rel",
+		"#/definitions/spell/links/1/method: method FETCH is not one of GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS, no handler is generated for it",
+		"#/definitions/spell/links/1/href: variable #/definitions/missing matches no property nor definition",
+		"#/definitions/spell/links/1/href: query variable level matches no property nor definition, it's decoded as a string",
+		"#/definitions/spell/links/2/encType: the request body of media type multipart/form-data is not JSON, its schema is ignored",
+		"#/definitions/spell/links/2/mediaType: the response body of media type text/plain is not JSON, its schema is ignored",
+		"#/definitions/price/oneOf: oneOf has an integer and a number variant: integers match both, they're rejected",
+		"#/definitions/spell/definitions/unused~0~1x: definition unused~/x is never referenced",
+		"#/definitions/orphan: definition orphan is never referenced",
+	}
+	var issues []string
+	for _, issue := range sp.Lint() {
+		issues = append(issues, issue.String())
+	}
+	if !reflect.DeepEqual(issues, expected) {
+		t.Errorf("expected issues\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(issues, "\n"))
+	}
+}
+
+func TestLintNoIssue(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "definitions": {
+        "spell": {
+            "type": "object",
+            "$comment": "a spell",
+            "definitions": {
+                "name": {"type": "string"}
+            },
+            "properties": {
+                "name": {"$ref": "#/definitions/spell/definitions/name"}
+            },
+            "links": [
+                {"href": "/spells/{(#/definitions/spell/definitions/name)}", "method": "GET", "rel": "self", "targetSchema": {"$ref": "#/definitions/spell"}}
+            ]
+        }
+    },
+    "properties": {
+        "spell": {"$ref": "#/definitions/spell"}
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	if issues := sp.Lint(); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+}
+
+func TestLintRefCycle(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "a": {"$ref": "#/definitions/b"},
+        "b": {"$ref": "#/definitions/a"}
+    },
+    "properties": {
+        "spell": {"$ref": "#/definitions/a"}
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	// The cycle is reported once, like ParseRoutes does.
+	expectedIssues := []string{
+		`#/definitions/a/$ref: invalid $ref "#/definitions/b": $ref cycle #/definitions/a -> #/definitions/b -> #/definitions/a`,
+	}
+	issues := sp.Lint()
+	var issueStrings []string
+	for _, issue := range issues {
+		issueStrings = append(issueStrings, issue.String())
+	}
+	if !reflect.DeepEqual(expectedIssues, issueStrings) {
+		t.Errorf("expected %q, got %q", expectedIssues, issueStrings)
+	}
+	if _, err := sp.ParseRoutes(); err == nil || !strings.Contains(err.Error(), expectedIssues[0][len("#/definitions/a/$ref: "):]) {
+		t.Errorf("expected ParseRoutes to fail with the same error, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package dispel
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// hasAdditionalProperties returns true if one of the generated types has a catch-all AdditionalProperties field.
+func (t *Template) hasAdditionalProperties() bool {
+	for _, jtn := range t.ctx.Routes.JSONNamedTypes() {
+		if jo, ok := jtn.(JSONObject); ok && jo.AdditionalProperties != nil && !t.isExistingType(jo.TypeName()) {
+			return true
+		}
+	}
+	return false
+}
+
+// printAdditionalPropertiesMethods returns the methods encoding and decoding the AdditionalProperties field of jo
+// along with its other fields, so that unknown properties aren't lost.
+func (t *Template) printAdditionalPropertiesMethods(jo JSONObject) string {
+	sp := t.ctx.Schema
+	typeName := sp.JSONToGoType(jo, false)
+	valueType := sp.JSONToGoType(jo.AdditionalProperties, false)
+	recv := t.Varname(typeName)
+	switch recv {
+	case "data", "err", "props", "k", "v", "raw", "known":
+		recv = "o"
+	}
+	var known []string
+	for _, f := range jo.allFields() {
+		known = append(known, fmt.Sprintf("%q", f.Name))
+	}
+
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("// MarshalJSON implements the json.Marshaler interface.\n")
+	fmt.Fprintf(&buf, "// The AdditionalProperties are encoded along with the other properties of %s.\n", recv)
+	fmt.Fprintf(&buf, "func (%s %s) MarshalJSON() ([]byte, error) {\n", recv, typeName)
+	// plain has the fields of the type, but not its methods.
+	fmt.Fprintf(&buf, "type plain %s\ndata, err := json.Marshal(plain(%s))\n", typeName, recv)
+	fmt.Fprintf(&buf, "if err != nil || len(%s.AdditionalProperties) == 0 {\nreturn data, err\n}\n", recv)
+	_, _ = buf.WriteString("var props map[string]json.RawMessage\nif err := json.Unmarshal(data, &props); err != nil {\nreturn nil, err\n}\n")
+	fmt.Fprintf(&buf, "for k, v := range %s.AdditionalProperties {\n", recv)
+	_, _ = buf.WriteString("if _, ok := props[k]; ok {\ncontinue\n}\nraw, err := json.Marshal(v)\nif err != nil {\nreturn nil, err\n}\nprops[k] = raw\n}\nreturn json.Marshal(props)\n}\n\n")
+
+	_, _ = buf.WriteString("// UnmarshalJSON implements the json.Unmarshaler interface.\n")
+	fmt.Fprintf(&buf, "// The properties which are not fields of %s are decoded into its AdditionalProperties.\n", typeName)
+	fmt.Fprintf(&buf, "func (%s *%s) UnmarshalJSON(data []byte) error {\n", recv, typeName)
+	fmt.Fprintf(&buf, "type plain %s\nif err := json.Unmarshal(data, (*plain)(%s)); err != nil {\nreturn err\n}\n", typeName, recv)
+	_, _ = buf.WriteString("var props map[string]json.RawMessage\nif err := json.Unmarshal(data, &props); err != nil {\nreturn err\n}\n")
+	if len(known) > 0 {
+		fmt.Fprintf(&buf, "for _, known := range []string{%s} {\ndelete(props, known)\n}\n", strings.Join(known, ", "))
+	}
+	fmt.Fprintf(&buf, "%s.AdditionalProperties = nil\nif len(props) == 0 {\nreturn nil\n}\n", recv)
+	fmt.Fprintf(&buf, "%s.AdditionalProperties = make(map[string]%s, len(props))\n", recv, valueType)
+	fmt.Fprintf(&buf, "for k, raw := range props {\nvar v %s\nif err := json.Unmarshal(raw, &v); err != nil {\nreturn err\n}\n", valueType)
+	fmt.Fprintf(&buf, "%s.AdditionalProperties[k] = v\n}\nreturn nil\n}", recv)
+	return buf.String()
+}
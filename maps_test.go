@@ -0,0 +1,229 @@
+package dispel
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"reflect"
+	"testing"
+)
+
+const mapsSpellsSchema = `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "required": ["name"],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "levels": {
+                    "type": "object",
+                    "patternProperties": {
+                        "^[0-9]+$": {
+                            "type": "integer"
+                        }
+                    }
+                },
+                "notes": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            },
+            "additionalProperties": {
+                "type": "number"
+            },
+            "links": [
+                {
+                    "href": "/spells",
+                    "method": "POST",
+                    "rel": "create",
+                    "schema": {
+                        "$ref": "#/definitions/spell"
+                    }
+                }
+            ]
+        }
+    },
+    "properties": {
+        "spell": {
+            "$ref": "#/definitions/spell"
+        }
+    }
+}`
+
+func TestParseAdditionalProperties(t *testing.T) {
+	schema := getSchemaString(t, mapsSpellsSchema)
+	if t.Failed() {
+		return
+	}
+	sp := SchemaParser{RootSchema: schema, SortFields: true}
+	obj, err := sp.JSONTypeFromSchema("Spell", schema.Definitions["spell"], "#/definitions/spell")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expectedObj := JSONObject{
+		Name: "Spell",
+		ref:  "#/definitions/spell",
+		Fields: JSONFieldList{
+			{Name: "labels", Type: JSONMap{Values: JSONString{}}},
+			{Name: "levels", Type: JSONMap{Values: JSONInteger{}, KeyPattern: "^[0-9]+$"}},
+			{Name: "name", Type: JSONString{}, Required: true},
+			{Name: "notes", Type: JSONMap{Values: JSONObject{Name: "NotesValue"}}},
+		},
+		AdditionalProperties: JSONNumber{},
+	}
+	if !reflect.DeepEqual(expectedObj, obj) {
+		t.Errorf("expected %#v, got %#v", expectedObj, obj)
+	}
+
+	tests := []struct {
+		Schema   string
+		Expected JSONType
+	}{
+		// Patterns of different types hold any value.
+		{`{"patternProperties": {"^a": {"type": "string"}, "^b": {"type": "integer"}}}`, JSONMap{Values: JSONObject{Name: "SpellValue"}}},
+		// additionalProperties: false describes no properties.
+		{`{"type": "object", "additionalProperties": false}`, JSONObject{Name: "Spell"}},
+	}
+	for i, test := range tests {
+		schema := getSchemaString(t, test.Schema)
+		if t.Failed() {
+			return
+		}
+		jt, err := sp.JSONTypeFromSchema("Spell", schema, "")
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.Expected, jt) {
+			t.Errorf("%d: expected %#v, got %#v", i, test.Expected, jt)
+		}
+	}
+}
+
+func TestTemplateTypesWithAdditionalProperties(t *testing.T) {
+	schema := getSchemaString(t, mapsSpellsSchema)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema, SortFields: true}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx := &Context{
+		Prgm:                "dispel",
+		PkgName:             "handler",
+		Routes:              routes,
+		HandlerReceiverType: "*App",
+	}
+
+	expectedOut, err := format.Source([]byte(fmt.Sprintf(`// generated by %s; DO NOT EDIT
+
+package %s
+
+import "encoding/json"
+
+// Spell represents the data structure sent/received on the following routes:
+//
+//  * Request body of POST /spells
+type Spell struct {
+    Labels map[string]string `+"`"+`json:"labels,omitempty"`+"`"+`
+    Levels map[string]int `+"`"+`json:"levels,omitempty"`+"`"+`
+    Name string `+"`"+`json:"name"`+"`"+`
+    Notes map[string]interface{} `+"`"+`json:"notes,omitempty"`+"`"+`
+    AdditionalProperties map[string]float64 `+"`"+`json:"-"`+"`"+`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// The AdditionalProperties are encoded along with the other properties of s.
+func (s Spell) MarshalJSON() ([]byte, error) {
+    type plain Spell
+    data, err := json.Marshal(plain(s))
+    if err != nil || len(s.AdditionalProperties) == 0 {
+        return data, err
+    }
+    var props map[string]json.RawMessage
+    if err := json.Unmarshal(data, &props); err != nil {
+        return nil, err
+    }
+    for k, v := range s.AdditionalProperties {
+        if _, ok := props[k]; ok {
+            continue
+        }
+        raw, err := json.Marshal(v)
+        if err != nil {
+            return nil, err
+        }
+        props[k] = raw
+    }
+    return json.Marshal(props)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// The properties which are not fields of Spell are decoded into its AdditionalProperties.
+func (s *Spell) UnmarshalJSON(data []byte) error {
+    type plain Spell
+    if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+        return err
+    }
+    var props map[string]json.RawMessage
+    if err := json.Unmarshal(data, &props); err != nil {
+        return err
+    }
+    for _, known := range []string{"labels", "levels", "name", "notes"} {
+        delete(props, known)
+    }
+    s.AdditionalProperties = nil
+    if len(props) == 0 {
+        return nil
+    }
+    s.AdditionalProperties = make(map[string]float64, len(props))
+    for k, raw := range props {
+        var v float64
+        if err := json.Unmarshal(raw, &v); err != nil {
+            return err
+        }
+        s.AdditionalProperties[k] = v
+    }
+    return nil
+}
+`, ctx.Prgm, ctx.PkgName)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tmpl, err := NewTemplate(sp, typesTmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Generate(&buf, ctx); err != nil {
+		t.Error(err)
+		return
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Log(buf.String())
+		t.Error(err)
+		return
+	}
+	if string(expectedOut) != string(out) {
+		t.Errorf("expected %#v, got %#v", string(expectedOut), string(out))
+		return
+	}
+}
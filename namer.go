@@ -0,0 +1,190 @@
+package dispel
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// Namer names the Go identifiers of the code generated from a schema.
+//
+// The names of the schemas and links with the x-go-name keyword are taken from it instead.
+type Namer interface {
+	// SymbolName returns the exported Go identifier of s, a property, a definition or a route name,
+	// e.g SpellName for spell-name. It names the fields of the structs, among others.
+	SymbolName(s string) string
+	// RefName returns the name of the type of the schema at the canonical ref,
+	// e.g SpellName for #/definitions/spell/definitions/name.
+	RefName(ref string) string
+	// RouteName returns the name of the route of the href, e.g spells.one for /spells/{id}.
+	RouteName(href string) (string, error)
+	// HandlerFuncName returns the name of the handler func of the route routeName for the HTTP method,
+	// e.g getSpellsOne.
+	HandlerFuncName(method string, routeName string) string
+	// LinkTypeName returns the name of a type of a link of the resource named resource, the property
+	// or definition holding the link: kind is In for its request body, Out for its response body
+	// and Query for its query string, e.g CreateSpellIn.
+	LinkTypeName(rel string, resource string, kind string) string
+	// InnerTypeName returns the name of a type made from the type named name: kind is Input for its
+	// variant without readOnly properties, One for its items, Value for its values, Part for a member
+	// of its allOf and Variant for a variant of its union. i is the 1-based index of the member or variant,
+	// 0 otherwise, e.g ServerInput or PricePart1.
+	InnerTypeName(name string, kind string, i int) string
+	// ParamName returns the Go variable of the route param name, a variable of an href,
+	// e.g serverID for server-id. The fields of the Route* structs are its symbol name.
+	ParamName(name string) string
+}
+
+// DefaultInitialisms are the initialisms of a DefaultNamer without Initialisms.
+// They're those golint knows.
+var DefaultInitialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP", "HTTPS", "ID",
+	"IP", "JSON", "LHS", "QPS", "RAM", "RHS", "RPC", "SLA", "SMTP", "SQL", "SSH", "TCP",
+	"TLS", "TTL", "UDP", "UI", "UID", "UUID", "URI", "URL", "UTF8", "VM", "XML", "XMPP",
+	"XSRF", "XSS",
+}
+
+// DefaultNamer is the Namer of a SchemaParser whose Namer is nil.
+type DefaultNamer struct {
+	// Initialisms are the words written in all caps in the identifiers, e.g ID in ServerID for server_id.
+	// If nil, DefaultInitialisms are used; an empty slice disables them.
+	Initialisms []string
+}
+
+// SymbolName implements the Namer interface: the words of s, delimited by one of ".-_ " or
+// by a change of case like in spellName, are capitalized and joined; the initialisms are all caps,
+// e.g ServerURL for server-url.
+func (n DefaultNamer) SymbolName(s string) string {
+	initialisms := n.Initialisms
+	if initialisms == nil {
+		initialisms = DefaultInitialisms
+	}
+	var buf bytes.Buffer
+	for _, word := range symbolWords(s) {
+		upper := strings.ToUpper(word)
+		for _, initialism := range initialisms {
+			if upper == strings.ToUpper(initialism) {
+				word = upper
+				break
+			}
+		}
+		_, _ = buf.WriteString(capitalize(word))
+	}
+	return buf.String()
+}
+
+// symbolWords splits s into the words of a symbol name: they're delimited by one of ".-_ ",
+// and an uppercase rune following a lowercase rune or a digit starts a word.
+func symbolWords(s string) []string {
+	var (
+		words []string
+		word  []rune
+		prev  rune
+	)
+	for _, r := range s {
+		isDelimiter := strings.ContainsRune(".-_ ", r)
+		startsWord := unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev))
+		if (isDelimiter || startsWord) && len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+		if !isDelimiter {
+			word = append(word, r)
+		}
+		prev = r
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// RefName implements the Namer interface: a whole document is named after its file name,
+// a definition after its path in the definitions, joined by -, e.g spell-name.
+func (n DefaultNamer) RefName(ref string) string {
+	// Name a whole document after its file name
+	doc, fragment := splitFragment(ref)
+	if fragment == "" && doc != "" {
+		name := path.Base(doc)
+		return n.SymbolName(strings.TrimSuffix(name, path.Ext(name)))
+	}
+	return n.SymbolName(definitionsPathName("#" + fragment))
+}
+
+// RouteName implements the Namer interface: the segments of the path are joined by a dot,
+// and its variables are named one. The root path is named index.
+func (DefaultNamer) RouteName(href string) (string, error) {
+	return href2name(href)
+}
+
+// HandlerFuncName implements the Namer interface: it's the lowercased method followed by
+// the symbol name of the route.
+func (n DefaultNamer) HandlerFuncName(method string, routeName string) string {
+	return strings.ToLower(method) + n.SymbolName(routeName)
+}
+
+// LinkTypeName implements the Namer interface: it's the symbol names of rel and resource, followed by kind.
+func (n DefaultNamer) LinkTypeName(rel string, resource string, kind string) string {
+	return fmt.Sprintf("%s%s%s", n.SymbolName(rel), n.SymbolName(resource), kind)
+}
+
+// InnerTypeName implements the Namer interface: it's name followed by kind, and by i if it's not 0.
+func (DefaultNamer) InnerTypeName(name string, kind string, i int) string {
+	if i == 0 {
+		return name + kind
+	}
+	return fmt.Sprintf("%s%s%d", name, kind, i)
+}
+
+// ParamName implements the Namer interface: it's the symbol name of name with its first word lowercased,
+// e.g urlID for url-id.
+func (n DefaultNamer) ParamName(name string) string {
+	words := symbolWords(name)
+	if len(words) == 0 {
+		return ""
+	}
+	return strings.ToLower(words[0]) + n.SymbolName(strings.Join(words[1:], "-"))
+}
+
+// namer returns the Namer of the SchemaParser.
+func (sp *SchemaParser) namer() Namer {
+	if sp.Namer == nil {
+		return DefaultNamer{}
+	}
+	return sp.Namer
+}
+
+// paramName returns the Go variable of the route param name, whose schema is s.
+// The x-go-name of s is the symbol name of the param, if set.
+func (sp *SchemaParser) paramName(name string, s *Schema) string {
+	if s.GoName == "" {
+		return sp.namer().ParamName(name)
+	}
+	return unexported(s.GoName)
+}
+
+// unexported returns the Go identifier s with its leading uppercase letters lowercased,
+// e.g urlKey for URLKey; the last one is kept if it starts a word.
+func unexported(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsUpper(r) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(r)
+	}
+	return string(runes)
+}
+
+// goFieldName returns the name of the struct field of f.
+func (sp *SchemaParser) goFieldName(f JSONField) string {
+	if f.GoName != "" {
+		return f.GoName
+	}
+	return sp.namer().SymbolName(f.Name)
+}
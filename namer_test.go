@@ -0,0 +1,335 @@
+package dispel
+
+import (
+	"bytes"
+	"go/format"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const goNamedSpellsSchema = `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "x-go-name": "Incantation",
+            "required": ["spell_id"],
+            "properties": {
+                "spell_id": {"type": "string", "x-go-name": "ID"},
+                "level": {"type": "integer", "minimum": 1}
+            },
+            "links": [
+                {
+                    "href": "/spells",
+                    "method": "GET",
+                    "rel": "list",
+                    "x-go-name": "listAllSpells",
+                    "targetSchema": {"type": "array", "items": {"$ref": "#/definitions/spell"}}
+                },
+                {
+                    "href": "/spells",
+                    "method": "POST",
+                    "rel": "create",
+                    "schema": {"$ref": "#/definitions/spell"}
+                }
+            ]
+        }
+    },
+    "properties": {
+        "spell": {"$ref": "#/definitions/spell"}
+    }
+}`
+
+func TestTemplateWithGoNames(t *testing.T) {
+	tests := []struct {
+		Tmpl     string
+		Expected []string
+	}{
+		{typesTmpl, []string{
+			"type Incantation struct {\n\tID    string `json:\"spell_id\"`\n\tLevel *int   `json:\"level,omitempty\"`\n}",
+			"func (i *Incantation) Validate() error {",
+			"if i.Level != nil {",
+		}},
+		{handlerfuncsTmpl, []string{
+			"func (a *App) listAllSpells(w http.ResponseWriter, r *http.Request) (int, []Incantation, error) {",
+			"func (a *App) postSpells(w http.ResponseWriter, r *http.Request, vreq *Incantation) (int, error) {",
+		}},
+		{handlersTmpl, []string{
+			"status, vresp, err := a.listAllSpells(w, r)",
+			"status, err := a.postSpells(w, r, &vreq)",
+		}},
+	}
+	for _, test := range tests {
+		out := generateTemplate(t, goNamedSpellsSchema, test.Tmpl, nil)
+		if t.Failed() {
+			return
+		}
+		for _, expected := range test.Expected {
+			if !bytes.Contains(out, []byte(expected)) {
+				t.Errorf("expected %s in\n%s", expected, out)
+			}
+		}
+	}
+}
+
+// resourceFirstNamer names the types of the links after their resource first, and the routes
+// after their path, underscore-separated.
+type resourceFirstNamer struct {
+	DefaultNamer
+}
+
+func (n resourceFirstNamer) RouteName(href string) (string, error) {
+	name, err := href2name(href)
+	return strings.Replace(name, ".", "_", -1), err
+}
+
+func (n resourceFirstNamer) LinkTypeName(rel string, resource string, kind string) string {
+	return n.SymbolName(resource) + n.SymbolName(rel) + kind
+}
+
+func TestParseRoutesWithNamer(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "properties": {
+        "spell": {
+            "properties": {
+                "name": {"type": "string"}
+            },
+            "links": [
+                {"href": "/spells/{name}", "method": "GET", "rel": "self", "targetSchema": {"type": "object", "properties": {"name": {"type": "string"}}}},
+                {"href": "/spells", "method": "POST", "rel": "create", "schema": {"type": "object", "properties": {"name": {"type": "string"}}}}
+            ]
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema, Namer: resourceFirstNamer{}}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := map[string]string{
+		"spells":     "SpellCreateIn",
+		"spells_one": "SpellSelfOut",
+	}
+	for _, route := range routes {
+		typ := route.InType
+		if typ == nil {
+			typ = route.OutType
+		}
+		if name := sp.JSONToGoType(typ, false); name != expected[route.Name] {
+			t.Errorf("route %s: expected type %s, got %s", route.Name, expected[route.Name], name)
+		}
+	}
+	if len(routes) != len(expected) {
+		t.Errorf("expected %d routes, got %d", len(expected), len(routes))
+	}
+}
+
+func TestDefaultNamerSymbolName(t *testing.T) {
+	tests := []struct {
+		Initialisms []string
+		S           string
+		Expected    string
+	}{
+		{nil, "server_id", "ServerID"},
+		{nil, "homeUrl", "HomeURL"},
+		{nil, "http-port", "HTTPPort"},
+		{nil, "json.api servers", "JSONAPIServers"},
+		{nil, "HTTPServer", "HTTPServer"},
+		{nil, "spellName", "SpellName"},
+		{nil, "identity", "Identity"},
+		{nil, "utf8Name", "UTF8Name"},
+		{nil, "spells.one", "SpellsOne"},
+		{[]string{}, "server_id", "ServerId"},
+		{[]string{"SKU"}, "item-sku", "ItemSKU"},
+		{[]string{"SKU"}, "item-id", "ItemId"},
+	}
+	for _, test := range tests {
+		n := DefaultNamer{Initialisms: test.Initialisms}
+		if name := n.SymbolName(test.S); name != test.Expected {
+			t.Errorf("%s with %v: expected %s, got %s", test.S, test.Initialisms, test.Expected, name)
+		}
+	}
+}
+
+// innerNamer names the input variants with the New prefix, the items of arrays with the Item suffix,
+// and the route params with the p prefix.
+type innerNamer struct {
+	DefaultNamer
+}
+
+func (n innerNamer) InnerTypeName(name string, kind string, i int) string {
+	switch kind {
+	case "Input":
+		return "New" + name
+	case "One":
+		return name + "Item"
+	}
+	return n.DefaultNamer.InnerTypeName(name, kind, i)
+}
+
+func (n innerNamer) ParamName(name string) string {
+	return "p" + n.SymbolName(name)
+}
+
+const innerNamedSpellsSchema = `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "properties": {
+                "id": {"type": "string", "readOnly": true},
+                "level": {"type": "integer"},
+                "effects": {"type": "array", "items": {"properties": {"name": {"type": "string"}}}}
+            },
+            "links": [
+                {"href": "/spells", "method": "POST", "rel": "create", "schema": {"$ref": "#/definitions/spell"}},
+                {"href": "/spells/{id}", "method": "GET", "rel": "self", "targetSchema": {"$ref": "#/definitions/spell"}},
+                {
+                    "href": "/books/{book-id}/spells",
+                    "method": "GET",
+                    "rel": "list",
+                    "hrefSchema": {"properties": {"book-id": {"type": "string", "x-go-name": "BookKey"}}},
+                    "targetSchema": {"type": "array", "items": {"$ref": "#/definitions/spell"}}
+                }
+            ]
+        }
+    },
+    "properties": {
+        "spell": {"$ref": "#/definitions/spell"}
+    }
+}`
+
+func TestParseRoutesWithInnerNamer(t *testing.T) {
+	schema := getSchemaString(t, innerNamedSpellsSchema)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema, Namer: innerNamer{}}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expectedParams := map[string][]string{
+		"spells":           nil,
+		"spells.one":       {"pID"},
+		"books.one.spells": {"bookKey"},
+	}
+	for _, route := range routes {
+		var params []string
+		for _, rp := range route.RouteParams {
+			params = append(params, rp.Varname)
+		}
+		if !reflect.DeepEqual(params, expectedParams[route.Name]) {
+			t.Errorf("route %s: expected the params %v, got %v", route.Name, expectedParams[route.Name], params)
+		}
+		if route.Name != "spells" {
+			continue
+		}
+		in, ok := route.InType.(JSONObject)
+		if !ok || in.Name != "NewSpell" {
+			t.Errorf("expected the input type NewSpell, got %#v", route.InType)
+			continue
+		}
+		effects := in.Fields[len(in.Fields)-1].Type.(JSONArray)
+		if name := sp.JSONToGoType(effects, false); name != "[]EffectsItem" {
+			t.Errorf("expected the effects type []EffectsItem, got %s", name)
+		}
+	}
+	if len(routes) != len(expectedParams) {
+		t.Errorf("expected %d routes, got %d", len(expectedParams), len(routes))
+	}
+}
+
+const initialismsSchema = `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "server": {
+            "properties": {
+                "name": {"type": "string"},
+                "home": {"oneOf": [{"type": "string", "format": "uri"}, {"type": "integer"}]}
+            },
+            "links": [
+                {
+                    "href": "/servers/{server-id}/links/{url-id}",
+                    "method": "PUT",
+                    "rel": "update",
+                    "hrefSchema": {"properties": {"server-id": {"type": "string"}, "url-id": {"type": "integer"}}},
+                    "schema": {"$ref": "#/definitions/server"}
+                }
+            ]
+        }
+    },
+    "properties": {
+        "server": {"$ref": "#/definitions/server"}
+    }
+}`
+
+func TestTemplateWithInitialisms(t *testing.T) {
+	schema := getSchemaString(t, initialismsSchema)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema, GoTypes: map[string]string{"uri": "example.com/web.Url"}}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ctx := &Context{
+		Prgm:                "dispel",
+		PkgName:             "handler",
+		Routes:              routes,
+		HandlerReceiverType: "*App",
+	}
+	tests := []struct {
+		Tmpl     string
+		Expected []string
+	}{
+		{typesTmpl, []string{
+			"type Home struct {\n\tURL     *web.Url\n\tInteger *int\n}",
+		}},
+		{handlerfuncsTmpl, []string{
+			"func (a *App) putServersOneLinksOne(w http.ResponseWriter, r *http.Request, serverID string, urlID int, vreq *Server) (int, error) {",
+		}},
+		{handlersTmpl, []string{
+			"serverID := rpg.GetRouteParam(r, \"server-id\")",
+			"urlID, err := strconv.Atoi(",
+			"status, err := a.putServersOneLinksOne(w, r, serverID, urlID, &vreq)",
+		}},
+		{routesTmpl, []string{
+			"RouteServersOneLinksOne struct {\n\t\tServerID string\n\t\tURLID    int\n\t}",
+		}},
+	}
+	for _, test := range tests {
+		tmpl, err := NewTemplate(sp, test.Tmpl)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Generate(&buf, ctx); err != nil {
+			t.Error(err)
+			return
+		}
+		out, err := format.Source(buf.Bytes())
+		if err != nil {
+			t.Log(buf.String())
+			t.Error(err)
+			return
+		}
+		for _, expected := range test.Expected {
+			if !bytes.Contains(out, []byte(expected)) {
+				t.Errorf("expected %s in\n%s", expected, out)
+			}
+		}
+	}
+}
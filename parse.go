@@ -0,0 +1,404 @@
+package dispel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The formats of the schema documents read by ParseSchema.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// FormatOfPath returns the format of the schema document at path, after its extension:
+// FormatYAML for .yaml and .yml files, FormatJSON otherwise.
+func FormatOfPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatJSON
+}
+
+// Location is a position in a schema document.
+type Location struct {
+	// File is the path of the document, if it was read from a file.
+	File string
+	// Line and Column start at 1; they're 0 if unknown. Columns of JSON documents count bytes.
+	Line   int
+	Column int
+}
+
+// String returns the location formatted as file:line:column, e.g api.json:12:5, without its unknown parts.
+func (l Location) String() string {
+	var parts []string
+	if l.File != "" {
+		parts = append(parts, l.File)
+	}
+	if l.Line > 0 {
+		parts = append(parts, strconv.Itoa(l.Line))
+		if l.Column > 0 {
+			parts = append(parts, strconv.Itoa(l.Column))
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// ParseSchema reads a schema document from r, in the format FormatJSON or FormatYAML.
+//
+// A YAML document is converted to the Schema its JSON equivalent would be decoded to.
+// The errors in a YAML document are reported with the line of the faulty value,
+// and the syntax errors in a JSON document with their line and column.
+//
+// The location of the nodes of the document is recorded, so that the errors of a SchemaParser
+// refer to them.
+func ParseSchema(r io.Reader, format string) (*Schema, error) {
+	return parseSchema(r, format, "")
+}
+
+// ParseSchemaFile reads the schema document of the file at path, in the format given by its extension.
+// Its errors, and the errors of a SchemaParser about it, refer to path.
+func ParseSchemaFile(path string) (*Schema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	schema, err := parseSchema(f, FormatOfPath(path), path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return schema, nil
+}
+
+// parseSchema reads a schema document from r, in the given format.
+// file is the path of the document, if it's read from a file.
+func parseSchema(r io.Reader, format string, file string) (*Schema, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var schema Schema
+	switch format {
+	case FormatJSON:
+		if err := json.NewDecoder(bytes.NewReader(b)).Decode(&schema); err != nil {
+			if e, ok := err.(*json.SyntaxError); ok && e.Offset > 0 {
+				// The offset is past the faulty byte.
+				line, column := jsonOffsetPosition(b, e.Offset-1)
+				return nil, fmt.Errorf("json: line %d, column %d: %v", line, column, err)
+			}
+			return nil, err
+		}
+		schema.locations = jsonLocations(b, file)
+	case FormatYAML:
+		var doc yaml.Node
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+		data, err := yamlToJSON(&doc)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			line, err := locateYAMLError(&doc, reflect.TypeOf(schema))
+			return nil, fmt.Errorf("yaml: line %d: %v", line, err)
+		}
+		schema.locations = yamlLocations(&doc, file)
+	default:
+		return nil, fmt.Errorf("unsupported schema format %q", format)
+	}
+	return &schema, nil
+}
+
+// jsonLocations returns the location of the nodes of the JSON document data, by JSON pointer.
+// The location of an object member is the one of its key.
+func jsonLocations(data []byte, file string) map[string]Location {
+	locations := make(map[string]Location)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// nextOffset returns the offset of the next token, past the separators.
+	nextOffset := func() int64 {
+		offset := dec.InputOffset()
+		for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[offset]) != -1 {
+			offset++
+		}
+		return offset
+	}
+	locate := func(pointer string, offset int64) {
+		line, column := jsonOffsetPosition(data, offset)
+		locations[pointer] = Location{File: file, Line: line, Column: column}
+	}
+	var walk func(pointer string) error
+	walk = func(pointer string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				offset := nextOffset()
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				memberPointer := pointer + "/" + pointerToken(key.(string))
+				locate(memberPointer, offset)
+				if err := walk(memberPointer); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				itemPointer := pointer + "/" + strconv.Itoa(i)
+				locate(itemPointer, nextOffset())
+				if err := walk(itemPointer); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err
+	}
+	locate("", nextOffset())
+	// The document was decoded already: there's no syntax error.
+	_ = walk("")
+	return locations
+}
+
+// jsonOffsetPosition returns the line and column of the byte at offset in data.
+func jsonOffsetPosition(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// yamlLocations returns the location of the nodes of the YAML document n, by JSON pointer.
+// The location of a mapping value is the one of its key.
+func yamlLocations(n *yaml.Node, file string) map[string]Location {
+	locations := make(map[string]Location)
+	var walk func(n *yaml.Node, pointer string, loc Location)
+	walk = func(n *yaml.Node, pointer string, loc Location) {
+		locations[pointer] = loc
+		switch n.Kind {
+		case yaml.DocumentNode:
+			if len(n.Content) > 0 {
+				walk(n.Content[0], pointer, loc)
+			}
+		case yaml.AliasNode:
+			walk(n.Alias, pointer, loc)
+		case yaml.MappingNode:
+			for _, pair := range yamlMappingPairs(n) {
+				key := pair[0]
+				walk(pair[1], pointer+"/"+pointerToken(key.Value), Location{File: file, Line: key.Line, Column: key.Column})
+			}
+		case yaml.SequenceNode:
+			for i, item := range n.Content {
+				walk(item, pointer+"/"+strconv.Itoa(i), Location{File: file, Line: item.Line, Column: item.Column})
+			}
+		}
+	}
+	start := n
+	for start.Kind == yaml.DocumentNode && len(start.Content) > 0 {
+		start = start.Content[0]
+	}
+	walk(n, "", Location{File: file, Line: start.Line, Column: start.Column})
+	return locations
+}
+
+// yamlToJSON returns the JSON encoding of the YAML node n.
+//
+// The keys of the mappings are encoded as strings, and their merge keys (<<) are expanded;
+// an error is returned for the values which JSON can't represent, like infinite numbers.
+func yamlToJSON(n *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeYAMLAsJSON(&buf, n); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeYAMLAsJSON(buf *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			_, _ = buf.WriteString("null")
+			return nil
+		}
+		return writeYAMLAsJSON(buf, n.Content[0])
+	case yaml.AliasNode:
+		return writeYAMLAsJSON(buf, n.Alias)
+	case yaml.SequenceNode:
+		_ = buf.WriteByte('[')
+		for i, item := range n.Content {
+			if i > 0 {
+				_ = buf.WriteByte(',')
+			}
+			if err := writeYAMLAsJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		_ = buf.WriteByte(']')
+		return nil
+	case yaml.MappingNode:
+		_ = buf.WriteByte('{')
+		first := true
+		for _, pair := range yamlMappingPairs(n) {
+			if !first {
+				_ = buf.WriteByte(',')
+			}
+			first = false
+			key, err := json.Marshal(pair[0].Value)
+			if err != nil {
+				return err
+			}
+			_, _ = buf.Write(key)
+			_ = buf.WriteByte(':')
+			if err := writeYAMLAsJSON(buf, pair[1]); err != nil {
+				return err
+			}
+		}
+		_ = buf.WriteByte('}')
+		return nil
+	}
+
+	var v interface{}
+	switch n.ShortTag() {
+	case "!!null":
+	case "!!bool", "!!int", "!!float":
+		if err := n.Decode(&v); err != nil {
+			return err
+		}
+		if f, ok := v.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			return fmt.Errorf("yaml: line %d: %s can't be represented in JSON", n.Line, n.Value)
+		}
+	default:
+		// Strings, and the other scalars, like timestamps, as they're written.
+		v = n.Value
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("yaml: line %d: %v", n.Line, err)
+	}
+	_, _ = buf.Write(b)
+	return nil
+}
+
+// yamlMappingPairs returns the key and value nodes of the mapping n.
+// The pairs of its merge keys come first, so that its own keys override them.
+func yamlMappingPairs(n *yaml.Node) [][2]*yaml.Node {
+	var merged, own [][2]*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.ShortTag() != "!!merge" {
+			own = append(own, [2]*yaml.Node{key, value})
+			continue
+		}
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, source := range sources {
+			if source.Kind == yaml.AliasNode {
+				source = source.Alias
+			}
+			if source.Kind == yaml.MappingNode {
+				merged = append(merged, yamlMappingPairs(source)...)
+			}
+		}
+	}
+	return append(merged, own...)
+}
+
+// locateYAMLError returns the line of the deepest node of n whose JSON equivalent fails to decode
+// in a value of type t, and the decoding error.
+func locateYAMLError(n *yaml.Node, t reflect.Type) (int, error) {
+	for n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	decodeErr := decodeYAMLAs(n, t)
+	if decodeErr == nil {
+		return 0, nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case n.Kind == yaml.MappingNode && (t.Kind() == reflect.Struct || t.Kind() == reflect.Map):
+		for _, pair := range yamlMappingPairs(n) {
+			single := &yaml.Node{Kind: yaml.MappingNode, Content: pair[:]}
+			err := decodeYAMLAs(single, t)
+			if err == nil {
+				continue
+			}
+			// Look for the faulty value inside the value of this key, if its type is known.
+			var valueType reflect.Type
+			if t.Kind() == reflect.Map {
+				valueType = t.Elem()
+			} else {
+				f, ok := jsonField(t, pair[0].Value)
+				if !ok {
+					return pair[0].Line, err
+				}
+				valueType = f.Type
+			}
+			if line, valueErr := locateYAMLError(pair[1], valueType); valueErr != nil {
+				return line, valueErr
+			}
+			return pair[0].Line, err
+		}
+	case n.Kind == yaml.SequenceNode && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+		for _, item := range n.Content {
+			if line, err := locateYAMLError(item, t.Elem()); err != nil {
+				return line, err
+			}
+		}
+	}
+	return n.Line, decodeErr
+}
+
+// decodeYAMLAs decodes the JSON equivalent of the YAML node n in a new value of type t.
+func decodeYAMLAs(n *yaml.Node, t reflect.Type) error {
+	data, err := yamlToJSON(n)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, reflect.New(t).Interface())
+}
+
+// jsonField returns the field of the struct type t encoded with the JSON key name.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if strings.Split(f.Tag.Get("json"), ",")[0] == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
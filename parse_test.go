@@ -0,0 +1,210 @@
+package dispel
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatOfPath(t *testing.T) {
+	tests := []struct {
+		Path     string
+		Expected string
+	}{
+		{"schema.json", FormatJSON},
+		{"schemas/api.yaml", FormatYAML},
+		{"API.YML", FormatYAML},
+		{"schema", FormatJSON},
+	}
+	for _, test := range tests {
+		if format := FormatOfPath(test.Path); format != test.Expected {
+			t.Errorf("%s: expected %s, got %s", test.Path, test.Expected, format)
+		}
+	}
+}
+
+func TestParseSchemaYAML(t *testing.T) {
+	yamlSchema := `# The spells of the API.
+$schema: http://json-schema.org/draft-04/hyper-schema
+type: object
+definitions:
+  spell:
+    description: |
+      A spell.
+
+      It's cast by a wizard.
+    required: [name]
+    properties:
+      name: &string
+        type: string
+        maxLength: 20
+      element:
+        <<: *string
+        enum: [fire, water]
+      power:
+        type: integer
+        minimum: 0
+        exclusiveMinimum: true
+    links:
+      - href: /spells
+        method: POST
+        rel: create
+        schema:
+          $ref: '#/definitions/spell'
+properties:
+  spell:
+    $ref: '#/definitions/spell'
+`
+	jsonSchema := `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "description": "A spell.\n\nIt's cast by a wizard.\n",
+            "required": ["name"],
+            "properties": {
+                "name": {"type": "string", "maxLength": 20},
+                "element": {"type": "string", "maxLength": 20, "enum": ["fire", "water"]},
+                "power": {"type": "integer", "minimum": 0, "exclusiveMinimum": true}
+            },
+            "links": [
+                {
+                    "href": "/spells",
+                    "method": "POST",
+                    "rel": "create",
+                    "schema": {"$ref": "#/definitions/spell"}
+                }
+            ]
+        }
+    },
+    "properties": {
+        "spell": {"$ref": "#/definitions/spell"}
+    }
+}`
+	fromYAML, err := ParseSchema(strings.NewReader(yamlSchema), FormatYAML)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	fromJSON, err := ParseSchema(strings.NewReader(jsonSchema), FormatJSON)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// The nodes of the documents are at different locations.
+	fromJSON.locations, fromYAML.locations = nil, nil
+	if !reflect.DeepEqual(fromJSON, fromYAML) {
+		t.Errorf("expected %#v, got %#v", fromJSON, fromYAML)
+	}
+}
+
+func TestParseInvalidSchemaYAML(t *testing.T) {
+	tests := []struct {
+		Desc   string
+		Schema string
+		Line   string
+	}{
+		{"syntax error", "type: object\nproperties:\n  name:\n\ttype: string\n", "line 4"},
+		{"invalid keyword type", "type: object\nproperties:\n  name:\n    type: string\n    maxLength: long\n", "line 5"},
+		{"invalid type", "type: object\nproperties:\n  name:\n    type: 3\n", "line 4"},
+		{"invalid link", "type: object\nlinks:\n  - href: /spells\n    schema: spell\n", "line 4"},
+		{"infinite number", "type: object\nproperties:\n  power:\n    maximum: .inf\n", "line 4"},
+	}
+	for _, test := range tests {
+		_, err := ParseSchema(strings.NewReader(test.Schema), FormatYAML)
+		if err == nil {
+			t.Errorf("%s: expected an error", test.Desc)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.Line+":") {
+			t.Errorf("%s: expected an error at %s, got %v", test.Desc, test.Line, err)
+		}
+	}
+}
+
+func TestParseSchemaLocations(t *testing.T) {
+	tests := []struct {
+		Format    string
+		Schema    string
+		Locations map[string]Location
+	}{
+		{
+			FormatJSON,
+			"{\n  \"properties\": {\n    \"name\": {\"type\": \"string\"},\n    \"tags\": {\"type\": \"array\", \"oneOf\": [\n      {\"a/b\": 1}\n    ]}\n  }\n}\n",
+			map[string]Location{
+				"":                              {Line: 1, Column: 1},
+				"/properties":                   {Line: 2, Column: 3},
+				"/properties/name":              {Line: 3, Column: 5},
+				"/properties/name/type":         {Line: 3, Column: 14},
+				"/properties/tags/oneOf/0":      {Line: 5, Column: 7},
+				"/properties/tags/oneOf/0/a~1b": {Line: 5, Column: 8},
+			},
+		},
+		{
+			FormatYAML,
+			"base: &base\n  type: string\nproperties:\n  name:\n    <<: *base\n    maxLength: 3\n  tags:\n    oneOf:\n      - a/b: 1\n",
+			map[string]Location{
+				"":                              {Line: 1, Column: 1},
+				"/properties":                   {Line: 3, Column: 1},
+				"/properties/name":              {Line: 4, Column: 3},
+				"/properties/name/type":         {Line: 2, Column: 3},
+				"/properties/name/maxLength":    {Line: 6, Column: 5},
+				"/properties/tags/oneOf/0":      {Line: 9, Column: 9},
+				"/properties/tags/oneOf/0/a~1b": {Line: 9, Column: 9},
+			},
+		},
+	}
+	for _, test := range tests {
+		schema, err := ParseSchema(strings.NewReader(test.Schema), test.Format)
+		if err != nil {
+			t.Errorf("%s: %v", test.Format, err)
+			continue
+		}
+		for pointer, expected := range test.Locations {
+			if loc := schema.locations[pointer]; loc != expected {
+				t.Errorf("%s: expected %q at %v, got %v", test.Format, pointer, expected, loc)
+			}
+		}
+	}
+}
+
+func TestParseInvalidSchemaJSON(t *testing.T) {
+	_, err := ParseSchema(strings.NewReader("{\n  \"type\": \"object\",\n  \"properties\": {,}\n}\n"), FormatJSON)
+	if err == nil {
+		t.Error("expected an error")
+		return
+	}
+	if !strings.Contains(err.Error(), "line 3, column 18:") {
+		t.Errorf("expected an error at line 3, column 18, got %v", err)
+	}
+}
+
+func TestParseKeywordSchema(t *testing.T) {
+	schema, err := ParseSchema(strings.NewReader(`{
+  "type": "object",
+  "additionalProperties": {
+    "properties": {
+      "zz": {"type": "string"},
+      "aa": {"type": "array"}
+    }
+  }
+}`), FormatJSON)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	sub, err := schema.AdditionalPropertiesSchema()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if names := sub.PropertyNames(); !reflect.DeepEqual([]string{"zz", "aa"}, names) {
+		t.Errorf("expected properties in document order, got %v", names)
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	_, err = sp.JSONTypeFromSchema("Spell", schema, "")
+	expectedErr := "6:7: #/additionalProperties/properties/aa: schema: missing items property for type array"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("expected the error %q, got %v", expectedErr, err)
+	}
+}
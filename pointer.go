@@ -0,0 +1,63 @@
+package dispel
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// pointerToken escapes s as a reference token of a JSON pointer.
+func pointerToken(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// errInvalidPointerEscape is returned for a reference token of a JSON pointer with a ~ not followed by 0 or 1.
+var errInvalidPointerEscape = errors.New("invalid escape sequence")
+
+// parsePointer returns the reference tokens of the JSON pointer p, as they're escaped in p,
+// e.g definitions and a~1b for /definitions/a~1b.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, fmt.Errorf("JSON pointer %q doesn't start with /", p)
+	}
+	tokens := strings.Split(p[1:], "/")
+	for _, token := range tokens {
+		for i := 0; i < len(token); i++ {
+			if token[i] == '~' && (i+1 == len(token) || (token[i+1] != '0' && token[i+1] != '1')) {
+				return nil, fmt.Errorf("reference token %q: %v", token, errInvalidPointerEscape)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// resolvePointer returns the schema at the JSON pointer p of the schema document doc, e.g /definitions/a~1b
+// for the definition named a/b, /items or /links/2/schema.
+//
+// The pointer follows the structure of the JSON document: it can address any schema nested in doc,
+// including the values of additionalProperties, additionalItems and dependencies which are schemas.
+// A nil schema is returned, without error, if p doesn't address a schema.
+func resolvePointer(doc *Schema, p string) (*Schema, error) {
+	tokens, err := parsePointer(p)
+	if err != nil {
+		return nil, err
+	}
+	s := doc
+	for len(tokens) > 0 {
+		// The keywords holding schemas are followed by 0, 1 or 2 tokens, e.g /items, /allOf/0 or /links/0/schema.
+		var sub *Schema
+		n := 0
+		subschemas := s.subschemas()
+		for i := 1; i <= len(tokens) && i <= 3 && sub == nil; i++ {
+			sub, n = subschemas["/"+strings.Join(tokens[:i], "/")], i
+		}
+		if sub == nil {
+			return nil, nil
+		}
+		s, tokens = sub, tokens[n:]
+	}
+	return s, nil
+}
@@ -0,0 +1,149 @@
+package dispel
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		Pointer  string
+		Expected []string
+		Valid    bool
+	}{
+		{"", nil, true},
+		{"/", []string{""}, true},
+		{"/definitions/a~1b", []string{"definitions", "a~1b"}, true},
+		{"/definitions/a~0b", []string{"definitions", "a~0b"}, true},
+		{"/definitions/a~2b", nil, false},
+		{"/definitions/a~", nil, false},
+		{"definitions", nil, false},
+	}
+	for _, test := range tests {
+		tokens, err := parsePointer(test.Pointer)
+		if valid := err == nil; valid != test.Valid {
+			t.Errorf("%q: expected valid %v, got error %v", test.Pointer, test.Valid, err)
+			continue
+		}
+		if fmt.Sprint(tokens) != fmt.Sprint(test.Expected) {
+			t.Errorf("%q: expected tokens %q, got %q", test.Pointer, test.Expected, tokens)
+		}
+	}
+}
+
+func TestResolveSchemaRefPointer(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "definitions": {
+        "a/b": {"description": "slash"},
+        "a~b": {"description": "tilde"},
+        "a%b": {"description": "percent"},
+        "list": {
+            "items": {"description": "items"},
+            "additionalItems": {"description": "additionalItems"}
+        },
+        "map": {
+            "additionalProperties": {
+                "properties": {
+                    "name": {"description": "additionalProperties name"}
+                }
+            },
+            "dependencies": {
+                "name": {"description": "dependency"},
+                "level": ["name"]
+            }
+        },
+        "composed": {
+            "allOf": [{"description": "allOf 0"}, {"description": "allOf 1"}],
+            "not": {"description": "not"}
+        }
+    },
+    "$defs": {
+        "new": {"description": "$defs"}
+    },
+    "properties": {
+        "spell": {
+            "links": [
+                {"href": "/spells", "method": "GET", "rel": "instances"},
+                {"href": "/spells", "method": "POST", "rel": "create", "schema": {"description": "link schema"}, "targetSchema": {"description": "link targetSchema"}}
+            ]
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	tests := []struct {
+		Ref         string
+		Description string
+	}{
+		{"#/definitions/a~1b", "slash"},
+		{"#/definitions/a~0b", "tilde"},
+		{"#/definitions/a%25b", "percent"},
+		{"#/definitions/list/items", "items"},
+		{"#/definitions/list/additionalItems", "additionalItems"},
+		{"#/definitions/map/additionalProperties/properties/name", "additionalProperties name"},
+		{"#/definitions/map/dependencies/name", "dependency"},
+		{"#/definitions/composed/allOf/1", "allOf 1"},
+		{"#/definitions/composed/not", "not"},
+		{"#/$defs/new", "$defs"},
+		{"#/properties/spell/links/1/schema", "link schema"},
+		{"#/properties/spell/links/1/targetSchema", "link targetSchema"},
+	}
+	for _, test := range tests {
+		s, err := sp.ResolveSchemaRef(test.Ref, schema)
+		if err != nil {
+			t.Errorf("%s: %v", test.Ref, err)
+			continue
+		}
+		if s.Description != test.Description {
+			t.Errorf("%s: expected the schema %q, got %q", test.Ref, test.Description, s.Description)
+		}
+	}
+	// The schema of additionalProperties is decoded once.
+	s1, _ := sp.ResolveSchemaRef("#/definitions/map/additionalProperties", schema)
+	s2, _ := schema.Definitions["map"].AdditionalPropertiesSchema()
+	if s1 == nil || s1 != s2 {
+		t.Errorf("expected the same additionalProperties schema, got %p and %p", s1, s2)
+	}
+
+	for _, ref := range []string{
+		"#/definitions/a/b",
+		"#/definitions/a~2b",
+		"#/definitions/map/dependencies/level",
+		"#/definitions/composed/allOf/2",
+		"#/properties/spell/links/0/schema",
+		"#/properties/spell/links",
+		"#/definitions/list/items/description",
+	} {
+		if _, err := sp.ResolveSchemaRef(ref, schema); err == nil {
+			t.Errorf("%s: expected an error", ref)
+		} else if _, ok := err.(InvalidSchemaRefError); !ok {
+			t.Errorf("%s: expected an InvalidSchemaRefError, got %#v", ref, err)
+		}
+	}
+}
+
+func TestResolveSchemaRefNotSchema(t *testing.T) {
+	schema, err := ParseSchema(strings.NewReader(`{"definitions": {"name": {"type": "string"}}}`), FormatJSON)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	tests := []struct {
+		Ref string
+		Msg string
+	}{
+		{"#/definitions/name/type", "value is not a valid Schema"},
+		{"#/definitions", "value is not a valid Schema"},
+		{"#/definitions/title", "invalid ref"},
+	}
+	for _, test := range tests {
+		_, err := sp.ResolveSchemaRef(test.Ref, schema)
+		if e, ok := err.(InvalidSchemaRefError); !ok || e.Msg != test.Msg {
+			t.Errorf("%s: expected an InvalidSchemaRefError %q, got %#v", test.Ref, test.Msg, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package dispel
+
+import (
+	"testing"
+)
+
+const treeSchema = `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "node": {
+            "required": ["name", "children", "root"],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/node"
+                    }
+                },
+                "parent": {
+                    "$ref": "#/definitions/node"
+                },
+                "root": {
+                    "$ref": "#/definitions/node"
+                },
+                "owner": {
+                    "$ref": "#/definitions/wizard"
+                }
+            }
+        },
+        "wizard": {
+            "properties": {
+                "tree": {
+                    "$ref": "#/definitions/node"
+                }
+            }
+        }
+    }
+}`
+
+func TestParseRecursiveType(t *testing.T) {
+	schema := getSchemaString(t, treeSchema)
+	if t.Failed() {
+		return
+	}
+	sp := SchemaParser{RootSchema: schema}
+	node, err := sp.JSONTypeFromSchema("Node", schema.Definitions["node"], "#/definitions/node")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// A required field referring to its own struct is a pointer.
+	expectedNode := "struct {\nName string `json:\"name\"`\nChildren []Node `json:\"children\"`\nParent *Node `json:\"parent,omitempty\"`\nRoot *Node `json:\"root\"`\nOwner *Wizard `json:\"owner,omitempty\"`\n}"
+	if goType := sp.JSONToGoType(node, true); goType != expectedNode {
+		t.Errorf("expected %s, got %s", expectedNode, goType)
+	}
+	expectedWizard := "struct {\nTree *Node `json:\"tree,omitempty\"`\n}"
+	if goType := sp.JSONToGoType(node.(JSONObject).Fields[4].Type, true); goType != expectedWizard {
+		t.Errorf("expected %s, got %s", expectedWizard, goType)
+	}
+	if len(sp.parsing) != 0 {
+		t.Errorf("expected no schema being parsed, got %v", sp.parsing)
+	}
+}
+
+func TestParseInvalidRecursiveType(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "definitions": {
+        "list": {
+            "type": "array",
+            "items": {
+                "$ref": "#/definitions/list"
+            }
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := SchemaParser{RootSchema: schema}
+	_, err := sp.JSONTypeFromSchema("List", schema.Definitions["list"], "#/definitions/list")
+	if _, ok := err.(InvalidSchemaError); !ok {
+		t.Errorf("expected an InvalidSchemaError, got %#v", err)
+	}
+}
+
+func TestResolveSchemaRefCycle(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "definitions": {
+        "a": {
+            "$ref": "#/definitions/b"
+        },
+        "b": {
+            "$ref": "#/definitions/c"
+        },
+        "c": {
+            "$ref": "#/definitions/a"
+        },
+        "d": {
+            "$ref": "#/definitions/c"
+        },
+        "self": {
+            "$ref": "#/definitions/self"
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := SchemaParser{RootSchema: schema}
+	// The cycle is reported the same from any of its schemas.
+	expected := `#/definitions/a/$ref: invalid $ref "#/definitions/b": $ref cycle #/definitions/a -> #/definitions/b -> #/definitions/c -> #/definitions/a`
+	for _, name := range []string{"a", "b", "c", "d"} {
+		_, err := sp.ResolveSchema(schema.Definitions[name])
+		if _, ok := err.(InvalidSchemaRefError); !ok {
+			t.Errorf("%s: expected an InvalidSchemaRefError, got %#v", name, err)
+			continue
+		}
+		if err.Error() != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, err)
+		}
+	}
+	expected = `#/definitions/self/$ref: invalid $ref "#/definitions/self": $ref cycle #/definitions/self -> #/definitions/self`
+	if _, err := sp.ResolveSchema(schema.Definitions["self"]); err == nil || err.Error() != expected {
+		t.Errorf("self: expected %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,291 @@
+package dispel
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// RefLoader is the interface implemented by objects that can load the schema documents
+// referenced by a $ref.
+type RefLoader interface {
+	// LoadSchema returns the schema of the document at uri.
+	// uri has no fragment. It is either absolute, or a path relative to the root schema's document.
+	LoadSchema(uri string) (*Schema, error)
+}
+
+// FileRefLoader is a RefLoader which loads the schema documents from JSON or YAML files,
+// depending on their extension.
+type FileRefLoader struct {
+	// Dir is the directory against which relative paths are resolved.
+	// Typically, this is the directory of the root schema.
+	Dir string
+	// BaseURI, if set, maps absolute URIs to files: the URIs starting with BaseURI are loaded
+	// from the same path relative to Dir.
+	// This is useful for schemas identifying themselves with an http(s) id.
+	BaseURI string
+}
+
+// LoadSchema implements the RefLoader interface.
+func (l *FileRefLoader) LoadSchema(uri string) (*Schema, error) {
+	p := uri
+	if l.BaseURI != "" && strings.HasPrefix(uri, l.BaseURI) {
+		p = strings.TrimPrefix(uri, l.BaseURI)
+	} else {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return nil, err
+		}
+		switch u.Scheme {
+		case "", "file":
+			p = u.Path
+		default:
+			return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+		}
+	}
+	p = filepath.FromSlash(p)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(l.Dir, p)
+	}
+	return ParseSchemaFile(p)
+}
+
+// resolveURI resolves the URI reference ref against the base URI.
+// Unlike url.URL.ResolveReference, relative bases are supported, and produce relative URIs.
+func resolveURI(base string, ref string) (string, error) {
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	if base == "" || refURL.IsAbs() {
+		return ref, nil
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	if baseURL.IsAbs() {
+		return baseURL.ResolveReference(refURL).String(), nil
+	}
+	switch {
+	case refURL.Path == "":
+		refURL.Path = baseURL.Path
+	case !strings.HasPrefix(refURL.Path, "/"):
+		refURL.Path = path.Join(path.Dir(baseURL.Path), refURL.Path)
+	}
+	return refURL.String(), nil
+}
+
+// splitFragment splits uri in its document part and its fragment.
+func splitFragment(uri string) (string, string) {
+	i := strings.Index(uri, "#")
+	if i == -1 {
+		return uri, ""
+	}
+	return uri[:i], uri[i+1:]
+}
+
+// subschemas returns the schemas directly nested in s, by their JSON pointer relative to s,
+// e.g /properties/name or /links/0/targetSchema.
+func (s *Schema) subschemas() map[string]*Schema {
+	a := make(map[string]*Schema)
+	for _, m := range []struct {
+		keyword string
+		schemas map[string]*Schema
+	}{
+		{"definitions", s.Definitions},
+		{"$defs", s.Defs},
+		{"properties", s.Properties},
+		{"patternProperties", s.PatternProperties},
+	} {
+		for name, sub := range m.schemas {
+			a["/"+m.keyword+"/"+pointerToken(name)] = sub
+		}
+	}
+	if s.Items != nil {
+		a["/items"] = s.Items
+	}
+	if s.Not != nil {
+		a["/not"] = s.Not
+	}
+	// The keywords which may hold a schema, or another value.
+	if sub, _ := s.AdditionalPropertiesSchema(); sub != nil {
+		a["/additionalProperties"] = sub
+	}
+	if sub, _ := s.keywordSchema("/additionalItems", s.AdditionalItems); sub != nil {
+		a["/additionalItems"] = sub
+	}
+	for name, v := range s.Dependencies {
+		p := "/dependencies/" + pointerToken(name)
+		if sub, _ := s.keywordSchema(p, v); sub != nil {
+			a[p] = sub
+		}
+	}
+	for _, l := range []struct {
+		keyword string
+		schemas []Schema
+	}{{"oneOf", s.OneOf}, {"anyOf", s.AnyOf}, {"allOf", s.AllOf}} {
+		for i := range l.schemas {
+			a[fmt.Sprintf("/%s/%d", l.keyword, i)] = &l.schemas[i]
+		}
+	}
+	for i, link := range s.Links {
+		for _, sub := range []struct {
+			keyword string
+			schema  *Schema
+		}{
+			{"schema", link.Schema},
+			{"targetSchema", link.TargetSchema},
+			{"hrefSchema", link.HRefSchema},
+			{"submissionSchema", link.SubmissionSchema},
+		} {
+			if sub.schema != nil {
+				a[fmt.Sprintf("/links/%d/%s", i, sub.keyword)] = sub.schema
+			}
+		}
+	}
+	return a
+}
+
+// schemaNode locates a schema in the loaded documents.
+type schemaNode struct {
+	// doc is the root schema of its document, loaded from uri.
+	doc *Schema
+	uri string
+	// pointer is the JSON pointer of the schema in its document, e.g /definitions/name.
+	pointer string
+}
+
+// indexDocument records the base URI, the draft and the JSON pointer of all the schemas of the document doc,
+// loaded from uri, and the schemas identified by an id.
+func (sp *SchemaParser) indexDocument(uri string, doc *Schema) {
+	if sp.documents == nil {
+		sp.documents = make(map[string]*Schema)
+		sp.baseURIs = make(map[*Schema]string)
+		sp.schemaIDs = make(map[string]*Schema)
+		sp.drafts = make(map[*Schema]Draft)
+		sp.nodes = make(map[*Schema]schemaNode)
+	}
+	sp.documents[uri] = doc
+	d := sp.documentDraft(doc)
+	var index func(s *Schema, base string, pointer string)
+	index = func(s *Schema, base string, pointer string) {
+		if _, ok := sp.baseURIs[s]; ok {
+			return
+		}
+		sp.drafts[s] = d
+		sp.nodes[s] = schemaNode{doc: doc, uri: uri, pointer: pointer}
+		if id := s.id(d); id != "" {
+			if id, err := resolveURI(base, id); err == nil {
+				sp.schemaIDs[strings.TrimSuffix(id, "#")] = s
+				base, _ = splitFragment(id)
+			}
+		}
+		sp.baseURIs[s] = base
+		for subPointer, sub := range s.subschemas() {
+			index(sub, base, pointer+subPointer)
+		}
+	}
+	index(doc, uri, "")
+}
+
+// locate returns the JSON pointer of the node of schema at the relative JSON pointer rel,
+// prefixed with the URI of its document if it's not the root schema's document,
+// and the location of the node in its source document.
+// Both are empty if schema isn't part of a loaded document.
+func (sp *SchemaParser) locate(schema *Schema, rel string) (string, Location) {
+	// Index the root schema's document, if needed.
+	sp.rootURI()
+	node, ok := sp.nodes[schema]
+	if !ok {
+		return "", Location{}
+	}
+	pointer := node.pointer + rel
+	uri := node.uri
+	if node.doc == sp.RootSchema {
+		uri = ""
+	}
+	return uri + "#" + pointer, node.doc.location(pointer)
+}
+
+// rootURI returns the URI of the root schema's document, indexing it if needed.
+func (sp *SchemaParser) rootURI() string {
+	if sp.documents == nil {
+		sp.indexDocument("", sp.RootSchema)
+	}
+	return sp.baseURIs[sp.RootSchema]
+}
+
+// baseURI returns the base URI against which the refs of s are resolved.
+func (sp *SchemaParser) baseURI(s *Schema) string {
+	rootURI := sp.rootURI()
+	if base, ok := sp.baseURIs[s]; ok {
+		return base
+	}
+	return rootURI
+}
+
+// canonicalRef returns the ref, relative to relSchema, as it identifies its schema
+// within the SchemaParser.
+//
+// Refs to the root schema's document are fragments only, e.g #/definitions/id.
+// Refs to other documents are the URI of the document with the fragment, e.g common.json#/definitions/error.
+func (sp *SchemaParser) canonicalRef(ref string, relSchema *Schema) string {
+	if ref == "" || (strings.HasPrefix(ref, "#") && sp.baseURI(relSchema) == sp.rootURI()) {
+		return ref
+	}
+	if _, ok := relSchema.Properties[ref]; ok {
+		return ref
+	}
+	uri, err := resolveURI(sp.baseURI(relSchema), ref)
+	if err != nil {
+		return ref
+	}
+	doc, fragment := splitFragment(uri)
+	if doc == sp.rootURI() {
+		return "#" + fragment
+	}
+	return uri
+}
+
+// refOf returns the canonical ref of the schema s, or "" if it has no $ref.
+func (sp *SchemaParser) refOf(s *Schema) string {
+	return sp.canonicalRef(s.Ref, s)
+}
+
+// loadDocument returns the schema of the document at uri, using the RefLoader if
+// it wasn't loaded yet.
+func (sp *SchemaParser) loadDocument(uri string, schemaRef string) (*Schema, error) {
+	if doc, ok := sp.documents[uri]; ok {
+		return doc, nil
+	}
+	if doc, ok := sp.schemaIDs[uri]; ok {
+		return doc, nil
+	}
+	if sp.RefLoader == nil {
+		return nil, InvalidSchemaRefError{Ref: schemaRef, Msg: fmt.Sprintf("no RefLoader to load %s", uri)}
+	}
+	doc, err := sp.RefLoader.LoadSchema(uri)
+	if err != nil {
+		return nil, InvalidSchemaRefError{Ref: schemaRef, Msg: fmt.Sprintf("can't load %s: %v", uri, err)}
+	}
+	sp.logf("loaded schema document %s", uri)
+	sp.indexDocument(uri, doc)
+	return doc, nil
+}
+
+// location returns the location of the node at pointer, e.g /definitions/name, in the source document of s,
+// or the location of its closest ancestor which has one.
+// The location is unknown if s isn't the root schema of a document read by ParseSchema.
+func (s *Schema) location(pointer string) Location {
+	for p := pointer; ; p = p[:strings.LastIndex(p, "/")] {
+		if loc, ok := s.locations[p]; ok {
+			return loc
+		}
+		if !strings.Contains(p, "/") {
+			return Location{}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package dispel
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// countingRefLoader counts the documents loaded by a RefLoader.
+type countingRefLoader struct {
+	RefLoader
+	loads map[string]int
+}
+
+func (l *countingRefLoader) LoadSchema(uri string) (*Schema, error) {
+	l.loads[uri]++
+	return l.RefLoader.LoadSchema(uri)
+}
+
+func TestParseSchemaWithExternalRefs(t *testing.T) {
+	schema := getSchema(t, "testdata/refs/api.json")
+	if t.Failed() {
+		return
+	}
+	rl := &countingRefLoader{
+		RefLoader: &FileRefLoader{Dir: "testdata/refs", BaseURI: "http://example.com/schemas/"},
+		loads:     make(map[string]int),
+	}
+	sp := &SchemaParser{RootSchema: schema, RefLoader: rl, SortFields: true}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	element := JSONObject{
+		Name: "Element",
+		ref:  "common/element.json",
+		Fields: JSONFieldList{
+			{Name: "name", Type: JSONString{}},
+		},
+	}
+	expectedIn := JSONObject{
+		Name: "Spell",
+		ref:  "#/definitions/spell",
+		Fields: JSONFieldList{
+			{Name: "caster", Type: JSONObject{
+				Name: "Caster",
+				ref:  "http://example.com/schemas/common/caster.json#/definitions/caster",
+				Fields: JSONFieldList{
+					{Name: "name", Type: JSONString{}},
+					{Name: "spells", Type: JSONArray{
+						Name:  "Spells",
+						Items: JSONString{ref: "http://example.com/schemas/common/caster.json#/definitions/caster-spell"},
+					}},
+				},
+			}},
+			{Name: "element", Type: element},
+			{Name: "name", Type: JSONString{ref: "#/definitions/spell/definitions/name"}},
+		},
+	}
+	expectedOut := JSONObject{
+		Name: "Error",
+		ref:  "common/errors.json#/definitions/error",
+		Fields: JSONFieldList{
+			{Name: "code", Type: JSONInteger{ref: "common/errors.json#/definitions/code"}},
+			{Name: "element", Type: element},
+			{Name: "message", Type: JSONString{}},
+		},
+	}
+
+	var route *Route
+	for i := range routes {
+		if routes[i].Method == "POST" {
+			route = &routes[i]
+		}
+	}
+	if route == nil {
+		t.Errorf("no POST route found in %#v", routes)
+		return
+	}
+	if !reflect.DeepEqual(expectedIn, route.InType) {
+		t.Errorf("expected %#v, got %#v", expectedIn, route.InType)
+	}
+	if !reflect.DeepEqual(expectedOut, route.OutType) {
+		t.Errorf("expected %#v, got %#v", expectedOut, route.OutType)
+	}
+
+	expectedLoads := map[string]int{
+		"common/errors.json":                            1,
+		"common/element.json":                           1,
+		"http://example.com/schemas/common/caster.json": 1,
+	}
+	if !reflect.DeepEqual(expectedLoads, rl.loads) {
+		t.Errorf("expected loads %v, got %v", expectedLoads, rl.loads)
+	}
+}
+
+func TestResolveMissingExternalRef(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "properties": {
+        "error": {
+            "$ref": "common/missing.json#/definitions/error"
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	tests := []struct {
+		Desc      string
+		RefLoader RefLoader
+	}{
+		{"no RefLoader", nil},
+		{"missing file", &FileRefLoader{Dir: "testdata/refs"}},
+	}
+	for _, test := range tests {
+		sp := &SchemaParser{RootSchema: schema, RefLoader: test.RefLoader}
+		_, err := sp.JSONTypeFromSchema("Spell", schema, "")
+		if _, ok := err.(InvalidSchemaRefError); !ok {
+			t.Errorf("%s: expected an InvalidSchemaRefError, got %#v", test.Desc, err)
+		}
+	}
+}
+
+func TestResolveURI(t *testing.T) {
+	tests := []struct {
+		Base     string
+		Ref      string
+		Expected string
+	}{
+		{"", "#/definitions/id", "#/definitions/id"},
+		{"", "common.json#/definitions/id", "common.json#/definitions/id"},
+		{"common/errors.json", "#/definitions/code", "common/errors.json#/definitions/code"},
+		{"common/errors.json", "element.json", "common/element.json"},
+		{"common/errors.json", "../api.json#/definitions/spell", "api.json#/definitions/spell"},
+		{"http://example.com/schemas/api.json", "common/errors.json", "http://example.com/schemas/common/errors.json"},
+		{"common/errors.json", "http://example.com/schemas/api.json", "http://example.com/schemas/api.json"},
+	}
+	for _, test := range tests {
+		uri, err := resolveURI(test.Base, test.Ref)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if uri != test.Expected {
+			t.Errorf("%q + %q: expected %q, got %q", test.Base, test.Ref, test.Expected, uri)
+		}
+	}
+}
+
+// stringRefLoader loads the schema documents from JSON strings, by URI.
+type stringRefLoader map[string]string
+
+func (l stringRefLoader) LoadSchema(uri string) (*Schema, error) {
+	doc, ok := l[uri]
+	if !ok {
+		return nil, fmt.Errorf("no document %s", uri)
+	}
+	return parseSchema(strings.NewReader(doc), FormatJSON, uri)
+}
+
+func TestExternalSchemaErrorLocation(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "properties": {
+        "spell": {"$ref": "common.json#/definitions/spell"},
+        "school": {"$ref": "common.json#/definitions/school"}
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema, RefLoader: stringRefLoader{
+		"common.json": "{\n  \"definitions\": {\n    \"spell\": {\"properties\": {\"name\": {\"pattern\": \"([\"}}},\n    \"school\": {\"$ref\": \"#/definitions/nope\"}\n  }\n}\n",
+	}}
+	tests := []struct {
+		Property string
+		Pointer  string
+		Location Location
+	}{
+		{"spell", "common.json#/definitions/spell/properties/name/pattern", Location{File: "common.json", Line: 3, Column: 39}},
+		{"school", "common.json#/definitions/school/$ref", Location{File: "common.json", Line: 4, Column: 16}},
+	}
+	for _, test := range tests {
+		_, err := sp.JSONTypeFromSchema("", schema.Properties[test.Property], "")
+		var pointer string
+		var loc Location
+		switch e := err.(type) {
+		case InvalidSchemaError:
+			pointer, loc = e.Pointer, e.Location
+		case InvalidSchemaRefError:
+			pointer, loc = e.Pointer, e.Location
+		default:
+			t.Errorf("%s: expected an InvalidSchemaError or an InvalidSchemaRefError, got %#v", test.Property, err)
+			continue
+		}
+		if pointer != test.Pointer || loc != test.Location {
+			t.Errorf("%s: expected an error at %s %v, got %s %v", test.Property, test.Pointer, test.Location, pointer, loc)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package dispel
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// paramType returns the type of the values of a route or query param of type typ,
+// or nil if its values are passed as strings.
+//
+// Integer, number, boolean, date-time and enum params are converted to their Go type.
+func (t *Template) paramType(typ JSONType) JSONType {
+	switch pt := t.ctx.Schema.ResolveType(nonNullType(typ)).(type) {
+	case JSONInteger, JSONNumber, JSONBoolean, JSONDateTime, JSONEnum:
+		return pt
+	}
+	return nil
+}
+
+// routeParamType returns the type of the values of the route param rp,
+// or nil if its values are passed as strings.
+func (t *Template) routeParamType(rp RouteParam) JSONType {
+	return t.paramType(rp.Type)
+}
+
+// RouteParamGoType returns the Go type of the route param rp, as passed to the handler funcs
+// and held by the Route* structs.
+func (t *Template) RouteParamGoType(rp RouteParam) string {
+	typ := t.routeParamType(rp)
+	if typ == nil {
+		return "string"
+	}
+	return t.ctx.Schema.JSONToGoType(typ, false)
+}
+
+// PrintRouteParamParse returns the statements of a generated handler which get the route param rp
+// and convert it to its Go type, in a variable named after its Varname.
+// The handler returns a 400 Bad Request status if the param is empty or can't be converted.
+func (t *Template) PrintRouteParamParse(rp RouteParam) string {
+	typ := t.routeParamType(rp)
+	raw := rp.Varname
+	if typ != nil {
+		raw += "Param"
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s := rpg.GetRouteParam(r, %q)\n", raw, rp.Name)
+	fmt.Fprintf(&buf, "if %s == \"\" {\nreturn http.StatusBadRequest, errors.New(%q)\n}\n", raw, fmt.Sprintf("empty route parameter %q", rp.Name))
+	if typ == nil {
+		return buf.String()
+	}
+	printParamParse(&buf, typ, t.RouteParamGoType(rp), raw, rp.Varname, fmt.Sprintf("route parameter %q", rp.Name), "return http.StatusBadRequest, %s\n")
+	return buf.String()
+}
+
+// printParamParse writes to buf the statements converting raw, the string value of the param desc,
+// to typ in the variable v of type goType.
+// fail is the format of the statement returning the conversion error.
+func printParamParse(buf *bytes.Buffer, typ JSONType, goType string, raw string, v string, desc string, fail string) {
+	invalidMsg := "invalid " + desc
+	var parseExpr, conv string
+	switch typ.(type) {
+	case JSONEnum:
+		fmt.Fprintf(buf, "%s := %s(%s)\nif !%s.IsValid() {\n", v, goType, raw, v)
+		fmt.Fprintf(buf, fail+"}\n", fmt.Sprintf("errors.New(%q)", invalidMsg))
+		return
+	case JSONInteger:
+		switch goType {
+		case "int":
+			parseExpr = fmt.Sprintf("strconv.Atoi(%s)", raw)
+		case "int64":
+			parseExpr = fmt.Sprintf("strconv.ParseInt(%s, 10, 64)", raw)
+		default:
+			parseExpr, conv = fmt.Sprintf("strconv.ParseInt(%s, 10, 32)", raw), goType
+		}
+	case JSONNumber:
+		if goType == "float64" {
+			parseExpr = fmt.Sprintf("strconv.ParseFloat(%s, 64)", raw)
+		} else {
+			parseExpr, conv = fmt.Sprintf("strconv.ParseFloat(%s, 32)", raw), goType
+		}
+	case JSONBoolean:
+		parseExpr = fmt.Sprintf("strconv.ParseBool(%s)", raw)
+	case JSONDateTime:
+		parseExpr = fmt.Sprintf("time.Parse(time.RFC3339, %s)", raw)
+	}
+	parsed := v
+	if conv != "" {
+		parsed += "Value"
+	}
+	fmt.Fprintf(buf, "%s, err := %s\nif err != nil {\n", parsed, parseExpr)
+	fmt.Fprintf(buf, fail+"}\n", fmt.Sprintf("fmt.Errorf(%q, err)", invalidMsg+": %v"))
+	if conv != "" {
+		fmt.Fprintf(buf, "%s := %s(%s)\n", v, conv, parsed)
+	}
+}
+
+// PrintRouteParamFormat returns the expression formatting expr, the value of the route param rp,
+// as a string.
+func (t *Template) PrintRouteParamFormat(rp RouteParam, expr string) string {
+	return formatParam(t.routeParamType(rp), t.RouteParamGoType(rp), expr)
+}
+
+// formatParam returns the expression formatting expr, a param value of type typ
+// and Go type goType, as a string.
+func formatParam(typ JSONType, goType string, expr string) string {
+	switch typ.(type) {
+	case JSONEnum:
+		return fmt.Sprintf("string(%s)", expr)
+	case JSONInteger:
+		if goType == "int" {
+			return fmt.Sprintf("strconv.Itoa(%s)", expr)
+		}
+		return fmt.Sprintf("strconv.FormatInt(int64(%s), 10)", expr)
+	case JSONNumber:
+		return fmt.Sprintf("strconv.FormatFloat(float64(%s), 'g', -1, 64)", expr)
+	case JSONBoolean:
+		return fmt.Sprintf("strconv.FormatBool(%s)", expr)
+	case JSONDateTime:
+		if strings.HasPrefix(expr, "*") {
+			expr = "(" + expr + ")"
+		}
+		return fmt.Sprintf("%s.Format(time.RFC3339)", expr)
+	}
+	if goType != "string" {
+		return fmt.Sprintf("string(%s)", expr)
+	}
+	return expr
+}
+
+// queryParam describes how a field of a query type is held by its struct.
+type queryParam struct {
+	JSONField
+	// GoType is the Go type of the values of the param.
+	GoType string
+	// IsArray tells whether the param has several values, appended to a slice.
+	IsArray bool
+	// IsPtr tells whether the field holds a pointer to the value.
+	IsPtr bool
+}
+
+// queryParams returns the params of the query type typ.
+func (t *Template) queryParams(typ JSONType) []queryParam {
+	sp := t.ctx.Schema
+	jo := sp.ResolveType(typ).(JSONObject)
+	params := make([]queryParam, len(jo.Fields))
+	for i, f := range jo.Fields {
+		qp := queryParam{JSONField: f}
+		if a, ok := nonNullType(f.Type).(JSONArray); ok {
+			qp.IsArray = true
+			qp.Type = a.Items
+			qp.GoType = sp.JSONToGoType(nonNullType(a.Items), false)
+		} else {
+			qp.GoType, qp.IsPtr = sp.jsonFieldGoType(f)
+		}
+		params[i] = qp
+	}
+	return params
+}
+
+// QueryTypes returns the types of the query string parameters of the routes.
+func (t *Template) QueryTypes() []JSONType {
+	var types []JSONType
+	visited := make(map[string]bool)
+	for _, route := range t.ctx.Routes {
+		if route.QueryType == nil {
+			continue
+		}
+		name := t.ctx.Schema.JSONToGoType(route.QueryType, false)
+		if !visited[name] {
+			visited[name] = true
+			types = append(types, route.QueryType)
+		}
+	}
+	sort.Sort(byGoTypeName{t.ctx.Schema, types})
+	return types
+}
+
+// byGoTypeName implements sorting by Go type name for a slice of JSONType objects.
+type byGoTypeName struct {
+	sp    *SchemaParser
+	types []JSONType
+}
+
+func (b byGoTypeName) Len() int      { return len(b.types) }
+func (b byGoTypeName) Swap(i, j int) { b.types[i], b.types[j] = b.types[j], b.types[i] }
+func (b byGoTypeName) Less(i, j int) bool {
+	return b.sp.JSONToGoType(b.types[i], false) < b.sp.JSONToGoType(b.types[j], false)
+}
+
+// PrintQueryDecodeFunc returns the decode() method of the query type typ, which sets its fields
+// from the values of a query string.
+// An error is returned by the method if a required param is missing or a value can't be converted.
+func (t *Template) PrintQueryDecodeFunc(typ JSONType) string {
+	typeName := t.ctx.Schema.JSONToGoType(typ, false)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// decode sets the fields of q from the values of a query string.\n")
+	fmt.Fprintf(&buf, "func (q *%s) decode(values url.Values) error {\n", typeName)
+	for _, qp := range t.queryParams(typ) {
+		missingMsg := fmt.Sprintf("missing query parameter %q", qp.Name)
+		if qp.IsArray {
+			if qp.Required {
+				fmt.Fprintf(&buf, "if len(values[%q]) == 0 {\nreturn errors.New(%q)\n}\n", qp.Name, missingMsg)
+			}
+			fmt.Fprintf(&buf, "for _, v := range values[%q] {\n", qp.Name)
+		} else {
+			fmt.Fprintf(&buf, "if v := values.Get(%q); v != \"\" {\n", qp.Name)
+		}
+		p := "v"
+		if pt := t.paramType(qp.Type); pt != nil {
+			p = "p"
+			printParamParse(&buf, pt, qp.GoType, "v", p, fmt.Sprintf("query parameter %q", qp.Name), "return %s\n")
+		} else if qp.GoType != "string" {
+			p = "p"
+			fmt.Fprintf(&buf, "%s := %s(v)\n", p, qp.GoType)
+		}
+		field := "q." + t.ctx.Schema.goFieldName(qp.JSONField)
+		switch {
+		case qp.IsArray:
+			fmt.Fprintf(&buf, "%s = append(%s, %s)\n}\n", field, field, p)
+		case qp.IsPtr:
+			fmt.Fprintf(&buf, "%s = &%s\n}", field, p)
+		default:
+			fmt.Fprintf(&buf, "%s = %s\n}", field, p)
+		}
+		if !qp.IsArray {
+			if qp.Required {
+				fmt.Fprintf(&buf, " else {\nreturn errors.New(%q)\n}", missingMsg)
+			}
+			fmt.Fprintln(&buf)
+		}
+	}
+	fmt.Fprintf(&buf, "return nil\n}\n")
+	return buf.String()
+}
+
+// PrintQueryValuesFunc returns the Values() method of the query type typ, which encodes its fields
+// as the values of a query string.
+func (t *Template) PrintQueryValuesFunc(typ JSONType) string {
+	typeName := t.ctx.Schema.JSONToGoType(typ, false)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Values implements the RouteQuery interface.\n")
+	fmt.Fprintf(&buf, "func (q %s) Values() url.Values {\nvalues := make(url.Values)\n", typeName)
+	for _, qp := range t.queryParams(typ) {
+		field := "q." + t.ctx.Schema.goFieldName(qp.JSONField)
+		pt := t.paramType(qp.Type)
+		switch {
+		case qp.IsArray:
+			fmt.Fprintf(&buf, "for _, v := range %s {\nvalues.Add(%q, %s)\n}\n", field, qp.Name, formatParam(pt, qp.GoType, "v"))
+		case qp.IsPtr:
+			fmt.Fprintf(&buf, "if %s != nil {\nvalues.Set(%q, %s)\n}\n", field, qp.Name, formatParam(pt, qp.GoType, "*"+field))
+		case !qp.Required:
+			fmt.Fprintf(&buf, "if %s != nil {\nvalues.Set(%q, %s)\n}\n", field, qp.Name, formatParam(pt, qp.GoType, field))
+		default:
+			fmt.Fprintf(&buf, "values.Set(%q, %s)\n", qp.Name, formatParam(pt, qp.GoType, field))
+		}
+	}
+	fmt.Fprintf(&buf, "return values\n}\n")
+	return buf.String()
+}
+
+// paramImports returns the packages imported to parse or format the route and query params.
+func (t *Template) paramImports() map[string]bool {
+	var types []JSONType
+	for _, route := range t.ctx.Routes {
+		for _, rp := range route.RouteParams {
+			types = append(types, rp.Type)
+		}
+	}
+	for _, typ := range t.QueryTypes() {
+		for _, qp := range t.queryParams(typ) {
+			types = append(types, qp.Type)
+		}
+	}
+	imports := make(map[string]bool)
+	for _, typ := range types {
+		switch t.paramType(typ).(type) {
+		case JSONInteger, JSONNumber, JSONBoolean:
+			imports["strconv"] = true
+		case JSONDateTime:
+			imports["time"] = true
+		}
+	}
+	return imports
+}
+
+// HandlersImports returns the packages imported by the generated handlers.
+func (t *Template) HandlersImports() []string {
+	imports := t.paramImports()
+	imports["net/http"] = true
+	for _, route := range t.ctx.Routes {
+		for _, rp := range route.RouteParams {
+			imports["errors"] = true
+			switch t.routeParamType(rp).(type) {
+			case nil, JSONEnum:
+			default:
+				imports["fmt"] = true
+			}
+		}
+	}
+	for _, typ := range t.QueryTypes() {
+		imports["net/url"] = true
+		for _, qp := range t.queryParams(typ) {
+			switch t.paramType(qp.Type).(type) {
+			case nil:
+			case JSONEnum:
+				imports["errors"] = true
+			default:
+				imports["fmt"] = true
+			}
+			if qp.Required {
+				imports["errors"] = true
+			}
+		}
+	}
+	return sortedImports(imports)
+}
+
+// RoutesImports returns the packages imported by the generated routes.
+func (t *Template) RoutesImports() []string {
+	imports := t.paramImports()
+	imports["net/url"] = true
+	return sortedImports(imports)
+}
+
+// HandlerFuncsImports returns the packages imported by the generated handler funcs.
+func (t *Template) HandlerFuncsImports() []string {
+	imports := map[string]bool{"net/http": true}
+	for _, route := range t.ctx.Routes {
+		if t.isExistingHandler(t.HandlerFuncName(route.Method, route.Name)) {
+			continue
+		}
+		for _, rp := range route.RouteParams {
+			if _, ok := t.routeParamType(rp).(JSONDateTime); ok {
+				imports["time"] = true
+			}
+		}
+	}
+	return sortedImports(imports)
+}
+
+// isExistingHandler returns true if the handler func named name is already implemented by the user.
+func (t *Template) isExistingHandler(name string) bool {
+	for _, h := range t.ctx.ExistingHandlers {
+		if h == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedImports(imports map[string]bool) []string {
+	a := make([]string, 0, len(imports))
+	for imp := range imports {
+		a = append(a, imp)
+	}
+	sort.Strings(a)
+	return a
+}
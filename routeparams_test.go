@@ -0,0 +1,345 @@
+package dispel
+
+import (
+	"bytes"
+	"go/format"
+	"testing"
+)
+
+const typedRouteParamsSchema = `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "definitions": {
+                "level": {"type": "integer"},
+                "mana": {"type": "integer", "format": "int32"},
+                "ratio": {"type": "number"},
+                "active": {"type": "boolean"},
+                "since": {"type": "string", "format": "date-time"}
+            },
+            "properties": {
+                "name": {"type": "string"}
+            },
+            "links": [
+                {
+                    "href": "/spells/{(#/definitions/spell/definitions/level)}/{(#/definitions/spell/definitions/mana)}/{(#/definitions/spell/definitions/ratio)}/{(#/definitions/spell/definitions/active)}/{(#/definitions/spell/definitions/since)}",
+                    "method": "GET",
+                    "rel": "self",
+                    "targetSchema": {"$ref": "#/definitions/spell"}
+                }
+            ]
+        }
+    },
+    "properties": {
+        "spell": {"$ref": "#/definitions/spell"}
+    }
+}`
+
+// generateTemplate generates the template tmplText for the routes of schemaString, and formats it.
+func generateTemplate(t *testing.T, schemaString string, tmplText string, existingHandlers []string) []byte {
+	schema := getSchemaString(t, schemaString)
+	if t.Failed() {
+		return nil
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	ctx := &Context{
+		Prgm:                "dispel",
+		PkgName:             "handler",
+		Routes:              routes,
+		HandlerReceiverType: "*App",
+		ExistingHandlers:    existingHandlers,
+	}
+	tmpl, err := NewTemplate(sp, tmplText)
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Generate(&buf, ctx); err != nil {
+		t.Error(err)
+		return nil
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Log(buf.String())
+		t.Error(err)
+		return nil
+	}
+	return out
+}
+
+func TestTemplateHandlersWithTypedRouteParams(t *testing.T) {
+	out := generateTemplate(t, typedRouteParamsSchema, handlersTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	expectedChecks := []string{
+		`import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)`,
+		`			spellLevelParam := rpg.GetRouteParam(r, "spell-level")
+			if spellLevelParam == "" {
+				return http.StatusBadRequest, errors.New("empty route parameter \"spell-level\"")
+			}
+			spellLevel, err := strconv.Atoi(spellLevelParam)
+			if err != nil {
+				return http.StatusBadRequest, fmt.Errorf("invalid route parameter \"spell-level\": %v", err)
+			}
+`,
+		`			spellManaValue, err := strconv.ParseInt(spellManaParam, 10, 32)
+			if err != nil {
+				return http.StatusBadRequest, fmt.Errorf("invalid route parameter \"spell-mana\": %v", err)
+			}
+			spellMana := int32(spellManaValue)
+`,
+		`			spellRatio, err := strconv.ParseFloat(spellRatioParam, 64)
+`,
+		`			spellActive, err := strconv.ParseBool(spellActiveParam)
+`,
+		`			spellSince, err := time.Parse(time.RFC3339, spellSinceParam)
+`,
+		`			status, vresp, err := a.getSpellsOneOneOneOneOne(w, r, spellLevel, spellMana, spellRatio, spellActive, spellSince)
+`,
+	}
+	for _, expectedCheck := range expectedChecks {
+		if !bytes.Contains(out, []byte(expectedCheck)) {
+			t.Errorf("expected %q in %s", expectedCheck, out)
+		}
+	}
+}
+
+func TestTemplateRoutesWithTypedRouteParams(t *testing.T) {
+	out := generateTemplate(t, typedRouteParamsSchema, routesTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	expectedChecks := []string{
+		`import (
+	"net/url"
+	"strconv"
+	"time"
+)`,
+		`		SpellLevel  int
+		SpellMana   int32
+		SpellRatio  float64
+		SpellActive bool
+		SpellSince  time.Time
+`,
+		`"spell-level", strconv.Itoa(r.SpellLevel), "spell-mana", strconv.FormatInt(int64(r.SpellMana), 10), "spell-ratio", strconv.FormatFloat(float64(r.SpellRatio), 'g', -1, 64), "spell-active", strconv.FormatBool(r.SpellActive), "spell-since", r.SpellSince.Format(time.RFC3339))`,
+	}
+	for _, expectedCheck := range expectedChecks {
+		if !bytes.Contains(out, []byte(expectedCheck)) {
+			t.Errorf("expected %q in %s", expectedCheck, out)
+		}
+	}
+}
+
+func TestTemplateHandlerFuncsWithTypedRouteParams(t *testing.T) {
+	out := generateTemplate(t, typedRouteParamsSchema, handlerfuncsTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	expectedChecks := []string{
+		`import (
+	"net/http"
+	"time"
+)`,
+		`func (a *App) getSpellsOneOneOneOneOne(w http.ResponseWriter, r *http.Request, spellLevel int, spellMana int32, spellRatio float64, spellActive bool, spellSince time.Time) (int, *Spell, error) {`,
+	}
+	for _, expectedCheck := range expectedChecks {
+		if !bytes.Contains(out, []byte(expectedCheck)) {
+			t.Errorf("expected %q in %s", expectedCheck, out)
+		}
+	}
+
+	// The time package isn't needed once the handler is implemented.
+	out = generateTemplate(t, typedRouteParamsSchema, handlerfuncsTmpl, []string{"getSpellsOneOneOneOneOne"})
+	if t.Failed() {
+		return
+	}
+	if bytes.Contains(out, []byte(`"time"`)) {
+		t.Errorf("unexpected time import in %s", out)
+	}
+}
+
+const queryParamsSchema = `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "definitions": {
+                "name": {"type": "string"}
+            },
+            "properties": {
+                "name": {"type": "string"}
+            },
+            "links": [
+                {
+                    "href": "/spells{?per_page}",
+                    "method": "GET",
+                    "rel": "instances",
+                    "schema": {
+                        "type": "object",
+                        "properties": {
+                            "page": {"type": "integer", "minimum": 1},
+                            "levels": {"type": "array", "items": {"type": "integer", "format": "int32"}},
+                            "since": {"type": "string", "format": "date-time"},
+                            "q": {"type": "string"}
+                        },
+                        "required": ["q"]
+                    },
+                    "targetSchema": {"type": "array", "items": {"$ref": "#/definitions/spell"}}
+                },
+                {
+                    "href": "/spells/{(#/definitions/spell/definitions/name)}",
+                    "method": "GET",
+                    "rel": "self",
+                    "targetSchema": {"$ref": "#/definitions/spell"}
+                }
+            ]
+        }
+    },
+    "properties": {
+        "spell": {"$ref": "#/definitions/spell"}
+    }
+}`
+
+func TestTemplateHandlersWithQueryParams(t *testing.T) {
+	out := generateTemplate(t, queryParamsSchema, handlersTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	expectedChecks := []string{
+		`import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)`,
+		`			var query InstancesSpellQuery
+			if err := query.decode(r.URL.Query()); err != nil {
+				return http.StatusBadRequest, err
+			}
+			if err := query.Validate(); err != nil {
+				return http.StatusBadRequest, err
+			}
+			status, vresp, err := a.getSpells(w, r, query)
+`,
+		`// decode sets the fields of q from the values of a query string.
+func (q *InstancesSpellQuery) decode(values url.Values) error {
+	if v := values.Get("page"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid query parameter \"page\": %v", err)
+		}
+		q.Page = &p
+	}
+	for _, v := range values["levels"] {
+		pValue, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid query parameter \"levels\": %v", err)
+		}
+		p := int32(pValue)
+		q.Levels = append(q.Levels, p)
+	}
+	if v := values.Get("since"); v != "" {
+		p, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("invalid query parameter \"since\": %v", err)
+		}
+		q.Since = &p
+	}
+	if v := values.Get("q"); v != "" {
+		q.Q = v
+	} else {
+		return errors.New("missing query parameter \"q\"")
+	}
+	if v := values.Get("per_page"); v != "" {
+		q.PerPage = &v
+	}
+	return nil
+}
+`,
+	}
+	for _, expectedCheck := range expectedChecks {
+		if !bytes.Contains(out, []byte(expectedCheck)) {
+			t.Errorf("expected %q in %s", expectedCheck, out)
+		}
+	}
+}
+
+func TestTemplateRoutesWithQueryParams(t *testing.T) {
+	out := generateTemplate(t, queryParamsSchema, routesTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	expectedChecks := []string{
+		`type RouteQuery interface {
+	Values() url.Values
+}`,
+		`	RouteSpells struct {
+		Query RouteQuery
+	}
+`,
+		`	RouteSpellsOne struct {
+		SpellName string
+	}
+`,
+		`func (r RouteSpells) Location(rr RouteReverser) *url.URL {
+	u := rr.ReverseRoute(routeSpells)
+	if r.Query != nil {
+		u.RawQuery = r.Query.Values().Encode()
+	}
+	return u
+}`,
+		`func (r RouteSpellsOne) Location(rr RouteReverser) *url.URL {
+	return rr.ReverseRoute(routeSpellsOne, "spell-name", r.SpellName)
+}`,
+		`// Values implements the RouteQuery interface.
+func (q InstancesSpellQuery) Values() url.Values {
+	values := make(url.Values)
+	if q.Page != nil {
+		values.Set("page", strconv.Itoa(*q.Page))
+	}
+	for _, v := range q.Levels {
+		values.Add("levels", strconv.FormatInt(int64(v), 10))
+	}
+	if q.Since != nil {
+		values.Set("since", (*q.Since).Format(time.RFC3339))
+	}
+	values.Set("q", q.Q)
+	if q.PerPage != nil {
+		values.Set("per_page", *q.PerPage)
+	}
+	return values
+}`,
+	}
+	for _, expectedCheck := range expectedChecks {
+		if !bytes.Contains(out, []byte(expectedCheck)) {
+			t.Errorf("expected %q in %s", expectedCheck, out)
+		}
+	}
+}
+
+func TestTemplateHandlerFuncsWithQueryParams(t *testing.T) {
+	out := generateTemplate(t, queryParamsSchema, handlerfuncsTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	expectedCheck := `func (a *App) getSpells(w http.ResponseWriter, r *http.Request, query InstancesSpellQuery) (int, []Spell, error) {`
+	if !bytes.Contains(out, []byte(expectedCheck)) {
+		t.Errorf("expected %q in %s", expectedCheck, out)
+	}
+}
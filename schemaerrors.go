@@ -0,0 +1,113 @@
+package dispel
+
+import (
+	"sort"
+	"strings"
+)
+
+// SchemaErrors is the list of the errors found in a schema by a SchemaParser collecting its errors.
+// It's sorted by location, then by JSON pointer; the errors without them, like
+// the *TypeRedefinitionError, are last.
+type SchemaErrors []error
+
+// Error returns the messages of the errors, one per line.
+func (e SchemaErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// add returns the errors with err, unless an identical error is in the list already.
+func (e SchemaErrors) add(err error) SchemaErrors {
+	for _, other := range e {
+		if other.Error() == err.Error() {
+			return e
+		}
+	}
+	return append(e, err)
+}
+
+func (e SchemaErrors) Len() int      { return len(e) }
+func (e SchemaErrors) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e SchemaErrors) Less(i, j int) bool {
+	li, pi := errorPosition(e[i])
+	lj, pj := errorPosition(e[j])
+	switch ri, rj := positionRank(li, pi), positionRank(lj, pj); {
+	case ri != rj:
+		return ri < rj
+	case li.File != lj.File:
+		return li.File < lj.File
+	case li.Line != lj.Line:
+		return li.Line < lj.Line
+	case li.Column != lj.Column:
+		return li.Column < lj.Column
+	case pi != pj:
+		return pi < pj
+	}
+	return e[i].Error() < e[j].Error()
+}
+
+// positionRank ranks the errors with a location first, then the ones with a JSON pointer only,
+// then the others.
+func positionRank(loc Location, pointer string) int {
+	switch {
+	case loc != Location{}:
+		return 0
+	case pointer != "":
+		return 1
+	}
+	return 2
+}
+
+// errorPosition returns the location and the JSON pointer of err, if it has them.
+func errorPosition(err error) (Location, string) {
+	switch e := err.(type) {
+	case InvalidSchemaError:
+		return e.Location, e.Pointer
+	case InvalidSchemaRefError:
+		return e.Location, e.Pointer
+	}
+	return Location{}, ""
+}
+
+// invalidRefErrors resolves the $refs of all the schemas of the root schema's document,
+// and of the documents they load, and returns the errors of the invalid ones and of the $ref cycles.
+func (sp *SchemaParser) invalidRefErrors() []error {
+	var errs []error
+	// Index the root schema's document, if needed.
+	sp.rootURI()
+	checked := make(map[*Schema]bool)
+	var check func(s *Schema)
+	check = func(s *Schema) {
+		if checked[s] {
+			return
+		}
+		checked[s] = true
+		if s.Ref != "" {
+			if _, err := sp.ResolveSchema(s); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		for _, sub := range s.subschemas() {
+			check(sub)
+		}
+	}
+	// Resolving the refs may load new documents, whose refs are checked in turn.
+	for {
+		var uris []string
+		for uri, doc := range sp.documents {
+			if !checked[doc] {
+				uris = append(uris, uri)
+			}
+		}
+		if len(uris) == 0 {
+			return errs
+		}
+		sort.Strings(uris)
+		for _, uri := range uris {
+			check(sp.documents[uri])
+		}
+	}
+}
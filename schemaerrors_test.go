@@ -0,0 +1,93 @@
+package dispel
+
+import (
+	"reflect"
+	"testing"
+)
+
+const schemaWithErrors = `{
+    "type": "object",
+    "definitions": {
+        "unused": {"$ref": "#/definitions/nope"}
+    },
+    "properties": {
+        "spell": {
+            "links": [
+                {"href": "/spells", "method": "GET", "rel": "listA", "targetSchema": {"type": "object", "properties": {"name": {"type": "string"}}}},
+                {"href": "/spells/1", "method": "GET", "rel": "showA", "targetSchema": {"type": "object", "properties": {"name": {"type": "string"}}}},
+                {"href": "/spells/2", "method": "GET", "rel": "showA"}
+            ]
+        },
+        "aSpell": {
+            "links": [
+                {"href": "/a-spells", "method": "GET", "rel": "list", "targetSchema": {"type": "object", "properties": {"level": {"type": "integer"}}}},
+                {"href": "/a-spells/1", "method": "GET", "rel": "show", "targetSchema": {"type": "object", "properties": {"level": {"type": "integer"}}}},
+                {"href": "/a-spells/2", "method": "POST", "rel": "create", "schema": {"$ref": "#/definitions/missing"}}
+            ]
+        }
+    }
+}`
+
+func TestParseRoutesCollectErrors(t *testing.T) {
+	schema := getSchemaString(t, schemaWithErrors)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema, CollectErrors: true}
+	routes, err := sp.ParseRoutes()
+	errs, ok := err.(SchemaErrors)
+	if !ok {
+		t.Errorf("expected SchemaErrors, got %#v", err)
+		return
+	}
+	expected := []string{
+		`#/definitions/unused/$ref: invalid $ref "#/definitions/nope": invalid ref`,
+		`#/properties/aSpell/links/2/schema/$ref: invalid $ref "#/definitions/missing": invalid ref`,
+		`#/properties/spell/links/2/rel: duplicate link "rel" showA`,
+		"type ListASpellOut defined multiple times",
+		"type ShowASpellOut defined multiple times",
+	}
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	if !reflect.DeepEqual(msgs, expected) {
+		t.Errorf("expected errors %q, got %q", expected, msgs)
+	}
+	// The routes without error are parsed.
+	if len(routes) != 4 {
+		t.Errorf("expected 4 routes, got %d", len(routes))
+	}
+}
+
+func TestParseRoutesFirstError(t *testing.T) {
+	schema := getSchemaString(t, schemaWithErrors)
+	if t.Failed() {
+		return
+	}
+	tests := []struct {
+		Desc     string
+		Modify   func(schema *Schema)
+		Expected string
+	}{
+		{"duplicate rel", func(schema *Schema) {}, `#/properties/spell/links/2/rel: duplicate link "rel" showA`},
+		{"type redefinitions", func(schema *Schema) {
+			schema.Properties["spell"].Links = schema.Properties["spell"].Links[:2]
+			schema.Properties["aSpell"].Links = schema.Properties["aSpell"].Links[:2]
+			// The definitions are resources too.
+			delete(schema.Definitions, "unused")
+		}, "type ListASpellOut defined multiple times"},
+	}
+	for _, test := range tests {
+		test.Modify(schema)
+		// The same error is returned each time.
+		for i := 0; i < 10; i++ {
+			sp := &SchemaParser{RootSchema: schema}
+			_, err := sp.ParseRoutes()
+			if err == nil || err.Error() != test.Expected {
+				t.Errorf("%s: expected error %q, got %v", test.Desc, test.Expected, err)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package dispel
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// unionHelpersDecl is the Go source code of the helpers used by the generated UnmarshalJSON() methods
+// of the unions without discriminator.
+const unionHelpersDecl = `// matchesJSON returns true if data has all the required properties,
+// and decodes to v without unknown properties.
+func matchesJSON(data []byte, v interface{}, required ...string) bool {
+	if len(required) > 0 {
+		var props map[string]json.RawMessage
+		if err := json.Unmarshal(data, &props); err != nil {
+			return false
+		}
+		for _, prop := range required {
+			if _, ok := props[prop]; !ok {
+				return false
+			}
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v) == nil
+}`
+
+// generatedUnions returns the unions whose type definition is generated.
+func (t *Template) generatedUnions() []JSONUnion {
+	var unions []JSONUnion
+	for _, jtn := range t.ctx.Routes.JSONNamedTypes() {
+		if u, ok := jtn.(JSONUnion); ok && !t.isExistingType(u.TypeName()) {
+			unions = append(unions, u)
+		}
+	}
+	return unions
+}
+
+// PrintUnionHelpersDecl returns the declaration of the helpers used to match the variants of unions,
+// or "" if no generated union needs them.
+func (t *Template) PrintUnionHelpersDecl() string {
+	for _, u := range t.generatedUnions() {
+		if u.Discriminator == "" {
+			return unionHelpersDecl
+		}
+	}
+	return ""
+}
+
+// unionImports returns the packages imported by the generated unions.
+func (t *Template) unionImports() []string {
+	var imports []string
+	for _, u := range t.generatedUnions() {
+		imports = append(imports, "encoding/json", "fmt")
+		if u.Discriminator == "" {
+			imports = append(imports, "bytes")
+		}
+	}
+	return imports
+}
+
+// printUnionTypeDef returns the Go type definition of a union, with a field for each of its variants,
+// and the methods encoding and decoding the variant it holds.
+func (t *Template) printUnionTypeDef(u JSONUnion) string {
+	sp := t.ctx.Schema
+	typeName := sp.JSONToGoType(u, false)
+	recv := t.Varname(typeName)
+	switch recv {
+	case "v", "data", "err", "matches", "discriminator":
+		recv = "u"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "type %s %s\n\n", typeName, sp.JSONToGoType(u, true))
+
+	_, _ = buf.WriteString("// MarshalJSON implements the json.Marshaler interface.\n")
+	fmt.Fprintf(&buf, "// It encodes the variant held by %s.\n", recv)
+	fmt.Fprintf(&buf, "func (%s %s) MarshalJSON() ([]byte, error) {\nswitch {\n", recv, typeName)
+	for _, variant := range u.Variants {
+		fieldExpr := recv + "." + sp.unionVariantName(variant)
+		fmt.Fprintf(&buf, "case %s != nil:\nreturn json.Marshal(%s)\n", fieldExpr, fieldExpr)
+	}
+	_, _ = buf.WriteString("}\nreturn []byte(\"null\"), nil\n}\n\n")
+
+	_, _ = buf.WriteString("// UnmarshalJSON implements the json.Unmarshaler interface.\n")
+	if u.Discriminator != "" {
+		fmt.Fprintf(&buf, "// The variant is picked using the %q property.\n", u.Discriminator)
+		fmt.Fprintf(&buf, "func (%s *%s) UnmarshalJSON(data []byte) error {\n", recv, typeName)
+		fmt.Fprintf(&buf, "var discriminator struct {\nValue string `json:%q`\n}\n", u.Discriminator)
+		_, _ = buf.WriteString("if err := json.Unmarshal(data, &discriminator); err != nil {\nreturn err\n}\n")
+		fmt.Fprintf(&buf, "*%s = %s{}\nswitch discriminator.Value {\n", recv, typeName)
+		for i, variant := range u.Variants {
+			goType, _ := sp.unionVariantGoType(variant)
+			fieldExpr := recv + "." + sp.unionVariantName(variant)
+			fmt.Fprintf(&buf, "case %q:\n%s = new(%s)\nreturn json.Unmarshal(data, %s)\n", u.DiscriminatorValues[i], fieldExpr, goType, fieldExpr)
+		}
+		fmt.Fprintf(&buf, "}\nreturn fmt.Errorf(\"invalid %s %s %%q\", discriminator.Value)\n}", typeName, u.Discriminator)
+		return buf.String()
+	}
+
+	if u.OneOf {
+		_, _ = buf.WriteString("// Exactly one variant must match data.\n")
+	} else {
+		_, _ = buf.WriteString("// The first variant matching data is picked.\n")
+	}
+	fmt.Fprintf(&buf, "func (%s *%s) UnmarshalJSON(data []byte) error {\n", recv, typeName)
+	fmt.Fprintf(&buf, "*%s = %s{}\n", recv, typeName)
+	if u.OneOf {
+		_, _ = buf.WriteString("var matches int\n")
+	}
+	for _, variant := range u.Variants {
+		goType, isPtr := sp.unionVariantGoType(variant)
+		var required []string
+		if jo, ok := sp.ResolveType(variant).(JSONObject); ok {
+			for _, f := range jo.allFields() {
+				if f.Required {
+					required = append(required, fmt.Sprintf("%q", f.Name))
+				}
+			}
+		}
+		args := append([]string{"data", "v"}, required...)
+		fmt.Fprintf(&buf, "if v := new(%s); matchesJSON(%s) {\n", goType, strings.Join(args, ", "))
+		fieldExpr := recv + "." + sp.unionVariantName(variant)
+		if isPtr {
+			fmt.Fprintf(&buf, "%s = v\n", fieldExpr)
+		} else {
+			fmt.Fprintf(&buf, "%s = *v\n", fieldExpr)
+		}
+		if u.OneOf {
+			_, _ = buf.WriteString("matches++\n}\n")
+		} else {
+			_, _ = buf.WriteString("return nil\n}\n")
+		}
+	}
+	if u.OneOf {
+		_, _ = buf.WriteString("switch matches {\ncase 1:\nreturn nil\ncase 0:\n")
+		fmt.Fprintf(&buf, "return fmt.Errorf(\"no variant of %s matches the JSON value\")\n}\n", typeName)
+		fmt.Fprintf(&buf, "*%s = %s{}\n", recv, typeName)
+		fmt.Fprintf(&buf, "return fmt.Errorf(\"%%d variants of %s match the JSON value\", matches)\n}", typeName)
+		return buf.String()
+	}
+	fmt.Fprintf(&buf, "return fmt.Errorf(\"no variant of %s matches the JSON value\")\n}", typeName)
+	return buf.String()
+}
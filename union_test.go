@@ -0,0 +1,425 @@
+package dispel
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"reflect"
+	"testing"
+)
+
+const equipmentsSchema = `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "weapon": {
+            "required": ["kind"],
+            "properties": {
+                "kind": {
+                    "type": "string",
+                    "enum": ["sword"]
+                },
+                "damage": {
+                    "type": "integer"
+                }
+            }
+        },
+        "armor": {
+            "required": ["kind"],
+            "properties": {
+                "kind": {
+                    "type": "string"
+                }
+            }
+        },
+        "equipment": {
+            "x-discriminator": "kind",
+            "oneOf": [
+                {
+                    "$ref": "#/definitions/weapon"
+                },
+                {
+                    "$ref": "#/definitions/armor"
+                }
+            ],
+            "links": [
+                {
+                    "href": "/equipments",
+                    "method": "POST",
+                    "rel": "create",
+                    "schema": {
+                        "properties": {
+                            "equipment": {
+                                "$ref": "#/definitions/equipment"
+                            },
+                            "price": {
+                                "anyOf": [
+                                    {
+                                        "type": "integer"
+                                    },
+                                    {
+                                        "type": "array",
+                                        "items": {
+                                            "type": "string"
+                                        }
+                                    }
+                                ]
+                            }
+                        }
+                    }
+                }
+            ]
+        }
+    },
+    "properties": {
+        "equipment": {
+            "$ref": "#/definitions/equipment"
+        }
+    }
+}`
+
+func TestParseUnion(t *testing.T) {
+	schema := getSchemaString(t, equipmentsSchema)
+	if t.Failed() {
+		return
+	}
+	sp := SchemaParser{RootSchema: schema, SortFields: true}
+	jt, err := sp.JSONTypeFromSchema("Equipment", schema.Definitions["equipment"], "#/definitions/equipment")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expectedUnion := JSONUnion{
+		Name: "Equipment",
+		ref:  "#/definitions/equipment",
+		Variants: []JSONType{
+			JSONObject{
+				Name: "Weapon",
+				ref:  "#/definitions/weapon",
+				Fields: JSONFieldList{
+					{Name: "damage", Type: JSONInteger{}},
+					{Name: "kind", Type: JSONEnum{Name: "WeaponKind", Values: []string{"sword"}}, Required: true},
+				},
+			},
+			JSONObject{
+				Name: "Armor",
+				ref:  "#/definitions/armor",
+				Fields: JSONFieldList{
+					{Name: "kind", Type: JSONString{}, Required: true},
+				},
+			},
+		},
+		OneOf:               true,
+		Discriminator:       "kind",
+		DiscriminatorValues: []string{"sword", "armor"},
+	}
+	if !reflect.DeepEqual(expectedUnion, jt) {
+		t.Errorf("expected %#v, got %#v", expectedUnion, jt)
+	}
+}
+
+func TestParseInvalidUnion(t *testing.T) {
+	tests := []struct {
+		Desc   string
+		Schema string
+	}{
+		{"oneOf and anyOf", `{"oneOf": [{"type": "string"}], "anyOf": [{"type": "integer"}]}`},
+		{"same variant names", `{"oneOf": [{"type": "string"}, {"type": "string", "format": "email"}]}`},
+		{"discriminated non-object", `{"x-discriminator": "kind", "oneOf": [{"type": "string"}]}`},
+		{"missing discriminator", `{"x-discriminator": "kind", "oneOf": [{"properties": {"name": {"type": "string"}}}]}`},
+		{"discriminator without value", `{"x-discriminator": "kind", "oneOf": [{"properties": {"kind": {"type": "string"}}}]}`},
+		{"same discriminator values", `{"x-discriminator": "kind", "oneOf": [
+            {"properties": {"kind": {"type": "string", "enum": ["a"]}, "name": {"type": "string"}}},
+            {"properties": {"kind": {"type": "string", "enum": ["a"]}}}
+        ]}`},
+	}
+	for _, test := range tests {
+		schema := getSchemaString(t, test.Schema)
+		if t.Failed() {
+			return
+		}
+		sp := SchemaParser{RootSchema: schema}
+		_, err := sp.JSONTypeFromSchema("Equipment", schema, "")
+		if _, ok := err.(InvalidSchemaError); !ok {
+			t.Errorf("%s: expected an InvalidSchemaError, got %#v", test.Desc, err)
+		}
+	}
+}
+
+func TestTemplateTypesWithUnion(t *testing.T) {
+	schema := getSchemaString(t, equipmentsSchema)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema, SortFields: true}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx := &Context{
+		Prgm:                "dispel",
+		PkgName:             "handler",
+		Routes:              routes,
+		HandlerReceiverType: "*App",
+		ExistingTypes:       []string{"WeaponKind"},
+	}
+
+	expectedOut, err := format.Source([]byte(fmt.Sprintf(`// generated by %s; DO NOT EDIT
+
+package %s
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+)
+
+// Armor represents the data structure sent/received on the following routes:
+//
+type Armor struct {
+    Kind string `+"`"+`json:"kind"`+"`"+`
+}
+
+// CreateEquipmentIn represents the data structure sent/received on the following routes:
+//
+//  * Request body of POST /equipments
+type CreateEquipmentIn struct {
+    Equipment *Equipment `+"`"+`json:"equipment,omitempty"`+"`"+`
+    Price *Price `+"`"+`json:"price,omitempty"`+"`"+`
+}
+
+// Equipment represents the data structure sent/received on the following routes:
+//
+type Equipment struct {
+    Weapon *Weapon
+    Armor *Armor
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// It encodes the variant held by e.
+func (e Equipment) MarshalJSON() ([]byte, error) {
+    switch {
+    case e.Weapon != nil:
+        return json.Marshal(e.Weapon)
+    case e.Armor != nil:
+        return json.Marshal(e.Armor)
+    }
+    return []byte("null"), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// The variant is picked using the "kind" property.
+func (e *Equipment) UnmarshalJSON(data []byte) error {
+    var discriminator struct {
+        Value string `+"`"+`json:"kind"`+"`"+`
+    }
+    if err := json.Unmarshal(data, &discriminator); err != nil {
+        return err
+    }
+    *e = Equipment{}
+    switch discriminator.Value {
+    case "sword":
+        e.Weapon = new(Weapon)
+        return json.Unmarshal(data, e.Weapon)
+    case "armor":
+        e.Armor = new(Armor)
+        return json.Unmarshal(data, e.Armor)
+    }
+    return fmt.Errorf("invalid Equipment kind %%q", discriminator.Value)
+}
+
+// Price represents the data structure sent/received on the following routes:
+//
+type Price struct {
+    Integer *int
+    PriceVariant2 []string
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// It encodes the variant held by p.
+func (p Price) MarshalJSON() ([]byte, error) {
+    switch {
+    case p.Integer != nil:
+        return json.Marshal(p.Integer)
+    case p.PriceVariant2 != nil:
+        return json.Marshal(p.PriceVariant2)
+    }
+    return []byte("null"), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// The first variant matching data is picked.
+func (p *Price) UnmarshalJSON(data []byte) error {
+    *p = Price{}
+    if v := new(int); matchesJSON(data, v) {
+        p.Integer = v
+        return nil
+    }
+    if v := new([]string); matchesJSON(data, v) {
+        p.PriceVariant2 = *v
+        return nil
+    }
+    return fmt.Errorf("no variant of Price matches the JSON value")
+}
+
+// Weapon represents the data structure sent/received on the following routes:
+//
+type Weapon struct {
+    Damage *int `+"`"+`json:"damage,omitempty"`+"`"+`
+    Kind WeaponKind `+"`"+`json:"kind"`+"`"+`
+}
+
+%s
+`, ctx.Prgm, ctx.PkgName, unionHelpersDecl)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tmpl, err := NewTemplate(sp, typesTmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Generate(&buf, ctx); err != nil {
+		t.Error(err)
+		return
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Log(buf.String())
+		t.Error(err)
+		return
+	}
+	if string(expectedOut) != string(out) {
+		t.Errorf("expected %#v, got %#v", string(expectedOut), string(out))
+		return
+	}
+}
+
+func TestTemplateTypesWithOverlappingNumericVariants(t *testing.T) {
+	out := generateTemplate(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "price": {
+            "properties": {
+                "exact": {"oneOf": [{"type": "integer"}, {"type": "number"}]},
+                "amount": {"type": ["integer", "number"]}
+            },
+            "links": [
+                {"href": "/prices", "method": "POST", "rel": "create", "schema": {"$ref": "#/definitions/price"}}
+            ]
+        }
+    },
+    "properties": {
+        "price": {"$ref": "#/definitions/price"}
+    }
+}`, typesTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	for _, expected := range []string{
+		// An integer matches both variants of oneOf: it's rejected.
+		"\tif v := new(int); matchesJSON(data, v) {\n\t\te.Integer = v\n\t\tmatches++\n\t}\n\tif v := new(float64); matchesJSON(data, v) {\n\t\te.Number = v\n\t\tmatches++\n\t}\n",
+		"\t*e = Exact{}\n\treturn fmt.Errorf(\"%d variants of Exact match the JSON value\", matches)\n",
+		// The first type listed matching an integer is picked.
+		"\tif v := new(int); matchesJSON(data, v) {\n\t\ta.Integer = v\n\t\treturn nil\n\t}\n\tif v := new(float64); matchesJSON(data, v) {\n\t\ta.Number = v\n\t\treturn nil\n\t}\n",
+	} {
+		if !bytes.Contains(out, []byte(expected)) {
+			t.Errorf("expected %s in\n%s", expected, out)
+		}
+	}
+}
+
+func TestTemplateTypesWithNullVariants(t *testing.T) {
+	out := generateTemplate(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "pet": {
+            "properties": {
+                "nickname": {"oneOf": [{"type": "string", "minLength": 2}, {"type": "null"}]},
+                "owner": {"anyOf": [{"type": "null"}, {"properties": {"name": {"type": "string"}}}]},
+                "tag": {"oneOf": [{"type": "string"}, {"type": "integer"}, {"type": "null"}]}
+            },
+            "links": [
+                {"href": "/pets", "method": "POST", "rel": "create", "schema": {"$ref": "#/definitions/pet"}}
+            ]
+        }
+    },
+    "properties": {
+        "pet": {"$ref": "#/definitions/pet"}
+    }
+}`, typesTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	// A null variant makes the other variant, or the union of the others, nullable.
+	for _, expected := range []string{
+		"type Pet struct {\n\tNickname *string `json:\"nickname,omitempty\"`\n\tOwner    *Owner  `json:\"owner,omitempty\"`\n\tTag      *Tag    `json:\"tag,omitempty\"`\n}",
+		"type Tag struct {\n\tString  *string\n\tInteger *int\n}",
+		"\tif p.Nickname != nil {\n\t\tif utf8.RuneCountInString(*p.Nickname) < 2 {\n",
+	} {
+		if !bytes.Contains(out, []byte(expected)) {
+			t.Errorf("expected %s in\n%s", expected, out)
+		}
+	}
+	typeCheck(t, out)
+}
+
+func TestTemplateTypesWithDiscriminatorEnums(t *testing.T) {
+	out := generateTemplate(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "cat": {
+            "required": ["kind"],
+            "properties": {
+                "kind": {"type": "string", "enum": ["cat"]},
+                "lives": {"type": "integer"}
+            }
+        },
+        "dog": {
+            "required": ["kind"],
+            "properties": {
+                "kind": {"type": "string", "enum": ["dog"]},
+                "breed": {"type": "string"}
+            }
+        },
+        "pet": {
+            "x-discriminator": "kind",
+            "oneOf": [{"$ref": "#/definitions/cat"}, {"$ref": "#/definitions/dog"}],
+            "links": [
+                {"href": "/pets", "method": "POST", "rel": "create", "schema": {"$ref": "#/definitions/pet"}}
+            ]
+        }
+    },
+    "properties": {
+        "pet": {"$ref": "#/definitions/pet"}
+    }
+}`, typesTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	// The inline enums of the discriminator are named after their variant.
+	for _, expected := range []string{
+		"type Cat struct {\n\tKind  CatKind `json:\"kind\"`\n\tLives *int    `json:\"lives,omitempty\"`\n}",
+		"type CatKind string",
+		"\tCatKindCat CatKind = \"cat\"\n",
+		"type Dog struct {\n\tKind  DogKind `json:\"kind\"`\n\tBreed *string `json:\"breed,omitempty\"`\n}",
+		"type DogKind string",
+		"\tDogKindDog DogKind = \"dog\"\n",
+		"\tcase \"cat\":\n\t\tp.Cat = new(Cat)\n",
+		"\tcase \"dog\":\n\t\tp.Dog = new(Dog)\n",
+	} {
+		if !bytes.Contains(out, []byte(expected)) {
+			t.Errorf("expected %s in\n%s", expected, out)
+		}
+	}
+	typeCheck(t, out)
+}
@@ -0,0 +1,491 @@
+package dispel
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// validationErrorsDecl is the Go source code of the types returned by the generated Validate() methods.
+const validationErrorsDecl = `// ValidationError represents a value violating a constraint of the JSON Schema.
+type ValidationError struct {
+	Field string ` + "`json:\"field\"`" + `
+	Msg   string ` + "`json:\"message\"`" + `
+}
+
+// Error implements the error interface.
+func (e ValidationError) Error() string {
+	return e.Field + ": " + e.Msg
+}
+
+// ValidationErrors is the list of violations returned by the Validate() methods.
+type ValidationErrors []ValidationError
+
+// Error implements the error interface.
+func (e ValidationErrors) Error() string {
+	var s string
+	for i, verr := range e {
+		if i > 0 {
+			s += "; "
+		}
+		s += verr.Error()
+	}
+	return s
+}
+
+// appendPrefixed appends the violations in err, prefixing their field with prefix.
+func (e ValidationErrors) appendPrefixed(prefix string, err error) ValidationErrors {
+	verrs, ok := err.(ValidationErrors)
+	if !ok {
+		return append(e, ValidationError{Field: prefix, Msg: err.Error()})
+	}
+	for _, verr := range verrs {
+		verr.Field = prefix + "." + verr.Field
+		e = append(e, verr)
+	}
+	return e
+}`
+
+// TypeNeedsValidation returns true if a Validate() method is generated for j.
+//
+// This is the case for named objects having fields with constraints,
+// or fields or embedded objects whose own type needs validation,
+// and for unions having a variant whose type needs validation.
+// Types already existing in the target package are assumed to have no Validate() method.
+func (t *Template) TypeNeedsValidation(j JSONType) bool {
+	return t.typeNeedsValidation(j, make(map[string]bool))
+}
+
+func (t *Template) typeNeedsValidation(j JSONType, visited map[string]bool) bool {
+	jtn, ok := t.ctx.Schema.ResolveType(j).(JSONTypeNamer)
+	if !ok || t.isExistingType(jtn.TypeName()) {
+		return false
+	}
+	switch jt := jtn.(type) {
+	case JSONObject:
+		if jt.isEmpty() || visited[jt.TypeName()] {
+			return false
+		}
+		visited[jt.TypeName()] = true
+		for _, e := range jt.Embedded {
+			if t.typeNeedsValidation(e, visited) {
+				return true
+			}
+		}
+		for _, f := range jt.Fields {
+			if t.valueNeedsValidation(f.Type, f.Constraints, visited) {
+				return true
+			}
+		}
+		if jt.AdditionalProperties != nil {
+			return t.valueNeedsValidation(jt.AdditionalProperties, jt.AdditionalPropertiesConstraints, visited)
+		}
+	case JSONUnion:
+		if visited[jt.TypeName()] {
+			return false
+		}
+		visited[jt.TypeName()] = true
+		for _, variant := range jt.Variants {
+			if t.typeNeedsValidation(variant, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// valueNeedsValidation returns true if a value of type j with the constraints c has something to check:
+// the constraints themselves, those of its items or values, or the Validate() method of its type.
+func (t *Template) valueNeedsValidation(j JSONType, c Constraints, visited map[string]bool) bool {
+	if !c.IsZero() {
+		return true
+	}
+	switch jt := t.ctx.Schema.ResolveType(nonNullType(j)).(type) {
+	case JSONArray:
+		return t.valueNeedsValidation(jt.Items, jt.ItemConstraints, visited)
+	case JSONMap:
+		return t.valueNeedsValidation(jt.Values, jt.ValueConstraints, visited)
+	}
+	return t.typeNeedsValidation(j, visited)
+}
+
+// HasValidation returns true if at least one of the generated types has a Validate() method.
+func (t *Template) HasValidation() bool {
+	for _, jtn := range t.ctx.Routes.JSONNamedTypes() {
+		if t.TypeNeedsValidation(jtn) {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintValidationErrorsDecl returns the declaration of the error types returned by the Validate() methods,
+// or "" if no generated type has one.
+func (t *Template) PrintValidationErrorsDecl() string {
+	if !t.HasValidation() {
+		return ""
+	}
+	return validationErrorsDecl
+}
+
+// validationImports returns the packages imported by the Validate() methods of the generated types.
+func (t *Template) validationImports() []string {
+	imports := make(map[string]bool)
+	for _, jtn := range t.ctx.Routes.JSONNamedTypes() {
+		if !t.TypeNeedsValidation(jtn) {
+			continue
+		}
+		vw := &validateWriter{t: t, imports: imports}
+		vw.writeValidateFunc(jtn)
+	}
+	var a []string
+	for imp := range imports {
+		a = append(a, imp)
+	}
+	return a
+}
+
+// PrintValidateFunc returns the Go source code of the Validate() method of j,
+// or "" if j doesn't need validation.
+func (t *Template) PrintValidateFunc(j JSONType) string {
+	if !t.TypeNeedsValidation(j) {
+		return ""
+	}
+	vw := &validateWriter{t: t, imports: make(map[string]bool)}
+	vw.writeValidateFunc(t.ctx.Schema.ResolveType(j))
+	return vw.buf.String()
+}
+
+// validateWriter writes the Validate() method of a generated type,
+// and records the packages it needs.
+type validateWriter struct {
+	t       *Template
+	buf     bytes.Buffer
+	imports map[string]bool
+	// vars holds the package-level declarations the method relies on.
+	vars bytes.Buffer
+}
+
+func (vw *validateWriter) writeValidateFunc(j JSONType) {
+	sp := vw.t.ctx.Schema
+	typeName := sp.JSONToGoType(j, false)
+	recv := vw.t.Varname(typeName)
+	switch recv {
+	case "errs", "err", "idx", "jdx", "item", "seen", "key", "u", "a":
+		recv = "v"
+	}
+
+	if u, ok := j.(JSONUnion); ok {
+		vw.writeUnionValidateFunc(u, typeName, recv)
+		return
+	}
+	jo := j.(JSONObject)
+	var body bytes.Buffer
+	for _, e := range jo.Embedded {
+		if !vw.t.TypeNeedsValidation(e) {
+			continue
+		}
+		// The violations of an embedded object are those of its parent.
+		fmt.Fprintf(&body, "if err := %s.%s.Validate(); err != nil {\nerrs = append(errs, err.(ValidationErrors)...)\n}\n", recv, sp.JSONToGoType(e, false))
+	}
+	for _, f := range jo.Fields {
+		goType, isPtr := sp.jsonFieldGoType(f)
+		fieldExpr := fmt.Sprintf("%s.%s", recv, sp.goFieldName(f))
+		checks := vw.fieldChecks(typeName, f, fieldExpr, isPtr)
+		if checks == "" {
+			continue
+		}
+		// Absent optional properties and null values have nothing to check.
+		_, nullable := f.Type.(JSONNullable)
+		if isPtr || ((!f.Required || nullable) && isNilableGoType(goType)) {
+			fmt.Fprintf(&body, "if %s != nil {\n%s}\n", fieldExpr, checks)
+		} else {
+			_, _ = body.WriteString(checks)
+		}
+	}
+	if jo.AdditionalProperties != nil {
+		// The violations of the additional properties are reported at their key.
+		check := valueCheck{typeName: typeName, name: "AdditionalProperties"}
+		_, _ = body.WriteString(vw.valuesChecks(check, recv+".AdditionalProperties", jo.AdditionalProperties, jo.AdditionalPropertiesConstraints, jo.AdditionalPropertiesPattern))
+	}
+
+	_, _ = vw.buf.Write(vw.vars.Bytes())
+	fmt.Fprintf(&vw.buf, "// Validate checks the %s against the constraints of its JSON Schema.\n", typeName)
+	fmt.Fprintf(&vw.buf, "func (%s *%s) Validate() error {\nvar errs ValidationErrors\n", recv, typeName)
+	_, _ = vw.buf.Write(body.Bytes())
+	_, _ = vw.buf.WriteString("if len(errs) > 0 {\nreturn errs\n}\nreturn nil\n}\n")
+}
+
+// writeUnionValidateFunc writes the Validate() method of the union u, which validates the variant it holds.
+func (vw *validateWriter) writeUnionValidateFunc(u JSONUnion, typeName string, recv string) {
+	fmt.Fprintf(&vw.buf, "// Validate checks the variant held by the %s against the constraints of its JSON Schema.\n", typeName)
+	fmt.Fprintf(&vw.buf, "func (%s *%s) Validate() error {\nswitch {\n", recv, typeName)
+	for _, variant := range u.Variants {
+		if !vw.t.TypeNeedsValidation(variant) {
+			continue
+		}
+		fieldExpr := recv + "." + vw.t.ctx.Schema.unionVariantName(variant)
+		fmt.Fprintf(&vw.buf, "case %s != nil:\nreturn %s.Validate()\n", fieldExpr, fieldExpr)
+	}
+	_, _ = vw.buf.WriteString("}\nreturn nil\n}\n")
+}
+
+// valueCheck describes a value whose constraints are checked.
+type valueCheck struct {
+	// typeName is the name of the type whose Validate() method checks the value.
+	typeName string
+	// name is the Go name of the value, unique in the type; it names the package-level declarations of its checks.
+	name string
+	// field is the Go expression of the Field of the violations of the value.
+	// It's empty for the additional properties of an object, whose violations are reported at their key.
+	field string
+	// depth is the number of arrays and maps holding the value in its field.
+	depth int
+}
+
+// loopVar returns the name of the variable base in the loops over the items or values at the depth of c,
+// so that the loops over nested arrays and maps don't shadow it.
+func (c valueCheck) loopVar(base string) string {
+	if c.depth == 0 {
+		return base
+	}
+	return base + strconv.Itoa(c.depth+1)
+}
+
+// fieldChecks returns the statements checking the constraints of f, whose value is accessed with fieldExpr.
+func (vw *validateWriter) fieldChecks(typeName string, f JSONField, fieldExpr string, isPtr bool) string {
+	valueExpr := fieldExpr
+	if isPtr {
+		valueExpr = "*" + fieldExpr
+	}
+	check := valueCheck{
+		typeName: typeName,
+		name:     vw.t.ctx.Schema.goFieldName(f),
+		field:    strconv.Quote(f.Name),
+	}
+	return vw.valueChecks(check, f.Type, f.Constraints, fieldExpr, valueExpr)
+}
+
+// valueChecks returns the statements checking the value of type typ against the constraints c.
+// The value is accessed with valueExpr; fieldExpr is the expression of its struct field, which may be a pointer to it.
+func (vw *validateWriter) valueChecks(check valueCheck, typ JSONType, c Constraints, fieldExpr string, valueExpr string) string {
+	sp := vw.t.ctx.Schema
+
+	var buf bytes.Buffer
+	fail := func(cond string, msg string) {
+		fmt.Fprintf(&buf, "if %s {\nerrs = append(errs, ValidationError{Field: %s, Msg: %q})\n}\n", cond, check.field, msg)
+	}
+
+	switch typ := sp.ResolveType(nonNullType(typ)).(type) {
+	case JSONString:
+		if typ.Format == "byte" {
+			// The length and pattern of the base64 encoded bytes aren't checked.
+			break
+		}
+		if c.MinLength > 0 {
+			vw.imports["unicode/utf8"] = true
+			fail(fmt.Sprintf("utf8.RuneCountInString(%s) < %d", valueExpr, c.MinLength), fmt.Sprintf("must be at least %d characters long", c.MinLength))
+		}
+		if c.MaxLength > 0 {
+			vw.imports["unicode/utf8"] = true
+			fail(fmt.Sprintf("utf8.RuneCountInString(%s) > %d", valueExpr, c.MaxLength), fmt.Sprintf("must be at most %d characters long", c.MaxLength))
+		}
+		if c.Pattern != "" {
+			vw.imports["regexp"] = true
+			patternVar := fmt.Sprintf("%s%sPattern", lowerFirst(check.typeName), check.name)
+			fmt.Fprintf(&vw.vars, "var %s = regexp.MustCompile(%s)\n\n", patternVar, strconv.Quote(c.Pattern))
+			fail(fmt.Sprintf("!%s.MatchString(%s)", patternVar, valueExpr), fmt.Sprintf("must match the pattern %s", c.Pattern))
+		}
+		if c.Format != "" {
+			fail(vw.formatCheck(check, c.Format, valueExpr))
+		}
+	case JSONInteger, JSONNumber:
+		_, isInt := typ.(JSONInteger)
+		bound := func(v float64) (expr string, lit string) {
+			lit = strconv.FormatFloat(v, 'g', -1, 64)
+			if isInt && v != math.Trunc(v) {
+				return fmt.Sprintf("float64(%s)", valueExpr), lit
+			}
+			return valueExpr, lit
+		}
+		if c.Minimum != nil {
+			expr, lit := bound(*c.Minimum)
+			if c.ExclusiveMinimum {
+				fail(fmt.Sprintf("%s <= %s", expr, lit), fmt.Sprintf("must be greater than %s", lit))
+			} else {
+				fail(fmt.Sprintf("%s < %s", expr, lit), fmt.Sprintf("must be greater than or equal to %s", lit))
+			}
+		}
+		if c.Maximum != nil {
+			expr, lit := bound(*c.Maximum)
+			if c.ExclusiveMaximum {
+				fail(fmt.Sprintf("%s >= %s", expr, lit), fmt.Sprintf("must be less than %s", lit))
+			} else {
+				fail(fmt.Sprintf("%s > %s", expr, lit), fmt.Sprintf("must be less than or equal to %s", lit))
+			}
+		}
+		if c.MultipleOf > 0 {
+			lit := strconv.FormatFloat(c.MultipleOf, 'g', -1, 64)
+			if isInt && c.MultipleOf == math.Trunc(c.MultipleOf) {
+				fail(fmt.Sprintf("%s%%%s != 0", valueExpr, lit), fmt.Sprintf("must be a multiple of %s", lit))
+			} else {
+				vw.imports["math"] = true
+				fail(fmt.Sprintf("math.Mod(float64(%s), %s) != 0", valueExpr, lit), fmt.Sprintf("must be a multiple of %s", lit))
+			}
+		}
+	case JSONArray:
+		if c.MinItems > 0 {
+			fail(fmt.Sprintf("len(%s) < %d", valueExpr, c.MinItems), fmt.Sprintf("must have at least %d items", c.MinItems))
+		}
+		if c.MaxItems > 0 {
+			fail(fmt.Sprintf("len(%s) > %d", valueExpr, c.MaxItems), fmt.Sprintf("must have at most %d items", c.MaxItems))
+		}
+		if c.UniqueItems {
+			itemType := sp.JSONToGoType(typ.Items, false)
+			var hashable bool
+			switch items := sp.ResolveType(typ.Items).(type) {
+			case JSONString:
+				hashable = items.Format != "byte"
+			case JSONEnum, JSONInteger, JSONNumber, JSONBoolean:
+				hashable = true
+			}
+			seen, item := check.loopVar("seen"), check.loopVar("item")
+			msg := "must not contain duplicate items"
+			if hashable {
+				fmt.Fprintf(&buf, "{\n%s := make(map[%s]bool)\nfor _, %s := range %s {\nif %s[%s] {\n", seen, itemType, item, valueExpr, seen, item)
+				fmt.Fprintf(&buf, "errs = append(errs, ValidationError{Field: %s, Msg: %q})\nbreak\n}\n%s[%s] = true\n}\n}\n", check.field, msg, seen, item)
+			} else {
+				vw.imports["reflect"] = true
+				idx, jdx := check.loopVar("idx"), check.loopVar("jdx")
+				label := "unique" + check.name
+				fmt.Fprintf(&buf, "%s:\nfor %s := range %s {\nfor %s := %s + 1; %s < len(%s); %s++ {\n", label, idx, valueExpr, jdx, idx, jdx, valueExpr, jdx)
+				fmt.Fprintf(&buf, "if reflect.DeepEqual(%s[%s], %s[%s]) {\n", valueExpr, idx, valueExpr, jdx)
+				fmt.Fprintf(&buf, "errs = append(errs, ValidationError{Field: %s, Msg: %q})\nbreak %s\n}\n}\n}\n", check.field, msg, label)
+			}
+		}
+		_, _ = buf.WriteString(vw.itemsChecks(check, valueExpr, typ.Items, typ.ItemConstraints))
+	case JSONMap:
+		_, _ = buf.WriteString(vw.valuesChecks(check, valueExpr, typ.Values, typ.ValueConstraints, typ.KeyPattern))
+	case JSONObject, JSONUnion:
+		if vw.t.TypeNeedsValidation(typ) {
+			fmt.Fprintf(&buf, "if err := %s.Validate(); err != nil {\nerrs = errs.appendPrefixed(%s, err)\n}\n", fieldExpr, check.field)
+		}
+	}
+	return buf.String()
+}
+
+// itemsChecks returns the loop checking the items of type typ of the array accessed with valueExpr
+// against the constraints c, or "" if they have nothing to check.
+// The violations of an item are reported at its index, e.g tags[2].
+func (vw *validateWriter) itemsChecks(check valueCheck, valueExpr string, typ JSONType, c Constraints) string {
+	idx := check.loopVar("idx")
+	item := valueCheck{
+		typeName: check.typeName,
+		name:     check.name + "Item",
+		field:    concatQuoted(check.field, "[") + "+strconv.Itoa(" + idx + ")+\"]\"",
+		depth:    check.depth + 1,
+	}
+	checks := vw.elemChecks(item, fmt.Sprintf("%s[%s]", valueExpr, idx), typ, c)
+	if checks == "" {
+		return ""
+	}
+	vw.imports["strconv"] = true
+	return fmt.Sprintf("for %s := range %s {\n%s}\n", idx, valueExpr, checks)
+}
+
+// valuesChecks returns the loop checking the values of type typ of the map accessed with valueExpr
+// against the constraints c, or "" if they have nothing to check.
+// The violations of a value are reported at its key, e.g labels.color.
+// If keyPattern isn't empty, only the values whose key matches it are checked.
+func (vw *validateWriter) valuesChecks(check valueCheck, valueExpr string, typ JSONType, c Constraints, keyPattern string) string {
+	key, item := check.loopVar("key"), check.loopVar("item")
+	value := valueCheck{
+		typeName: check.typeName,
+		name:     check.name + "Value",
+		field:    key,
+		depth:    check.depth + 1,
+	}
+	if check.field != "" {
+		value.field = concatQuoted(check.field, ".") + "+" + key
+	}
+	checks := vw.elemChecks(value, item, typ, c)
+	if checks == "" {
+		return ""
+	}
+	if keyPattern != "" {
+		vw.imports["regexp"] = true
+		patternVar := fmt.Sprintf("%s%sKeyPattern", lowerFirst(check.typeName), check.name)
+		fmt.Fprintf(&vw.vars, "var %s = regexp.MustCompile(%s)\n\n", patternVar, strconv.Quote(keyPattern))
+		checks = fmt.Sprintf("if %s.MatchString(%s) {\n%s}\n", patternVar, key, checks)
+	}
+	return fmt.Sprintf("for %s, %s := range %s {\n%s}\n", key, item, valueExpr, checks)
+}
+
+// elemChecks returns the statements checking the item or value of type typ accessed with elemExpr
+// against the constraints c. Null elements have nothing to check.
+//
+// Unlike struct fields, the elements of slices and maps are pointers only if they're nullable,
+// recursive objects included.
+func (vw *validateWriter) elemChecks(check valueCheck, elemExpr string, typ JSONType, c Constraints) string {
+	sp := vw.t.ctx.Schema
+	_, nullable := typ.(JSONNullable)
+	valueExpr := elemExpr
+	if nullable && !isNilableGoType(sp.JSONToGoType(nonNullType(typ), false)) {
+		valueExpr = "*" + elemExpr
+	}
+	checks := vw.valueChecks(check, typ, c, elemExpr, valueExpr)
+	if checks != "" && nullable {
+		return fmt.Sprintf("if %s != nil {\n%s}\n", elemExpr, checks)
+	}
+	return checks
+}
+
+// concatQuoted returns the Go expression concatenating the string expression expr and the constant s.
+// A quoted constant ending expr is merged with s, e.g "tags[" for "tags" and [.
+func concatQuoted(expr string, s string) string {
+	quoted := strconv.Quote(s)
+	if strings.HasSuffix(expr, `"`) {
+		return expr[:len(expr)-1] + quoted[1:]
+	}
+	return expr + "+" + quoted
+}
+
+// formatPatterns holds the regular expressions of the checked formats which have no parser in the standard library.
+var formatPatterns = map[string]string{
+	"duration": `^P(?:\d+W|(?:\d+Y)?(?:\d+M)?(?:\d+D)?(?:T(?:\d+H)?(?:\d+M)?(?:\d+(?:[.,]\d+)?S)?)?)$`,
+	"uuid":     `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`,
+}
+
+// formatCheck returns the condition which is true if the string value checked by check, accessed with valueExpr,
+// doesn't have the format, and the message of the violation.
+func (vw *validateWriter) formatCheck(check valueCheck, format string, valueExpr string) (string, string) {
+	switch format {
+	case "date":
+		vw.imports["time"] = true
+		return fmt.Sprintf("_, err := time.Parse(%q, %s); err != nil", "2006-01-02", valueExpr), "must be a date"
+	case "time":
+		vw.imports["time"] = true
+		return fmt.Sprintf("_, err := time.Parse(%q, %s); err != nil", "15:04:05Z07:00", valueExpr), "must be a time"
+	case "uri":
+		vw.imports["net/url"] = true
+		return fmt.Sprintf("u, err := url.Parse(%s); err != nil || !u.IsAbs()", valueExpr), "must be an absolute URI"
+	case "email":
+		vw.imports["net/mail"] = true
+		return fmt.Sprintf("a, err := mail.ParseAddress(%s); err != nil || a.Address != %s", valueExpr, valueExpr), "must be an email address"
+	default:
+		vw.imports["regexp"] = true
+		formatVar := fmt.Sprintf("%s%sFormat", lowerFirst(check.typeName), check.name)
+		fmt.Fprintf(&vw.vars, "var %s = regexp.MustCompile(%s)\n\n", formatVar, strconv.Quote(formatPatterns[format]))
+		return fmt.Sprintf("!%s.MatchString(%s)", formatVar, valueExpr), fmt.Sprintf("must be a %s", format)
+	}
+}
+
+// lowerFirst lowercases the first rune of s.
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return fmt.Sprintf("%c%s", unicode.ToLower(r), s[size:])
+}
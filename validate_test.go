@@ -0,0 +1,582 @@
+package dispel
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+const constrainedSpellsSchema = `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "spell": {
+            "required": ["name", "power"],
+            "links": [
+                {
+                    "href": "/spells",
+                    "method": "POST",
+                    "rel": "create",
+                    "schema": {
+                        "$ref": "#/definitions/spell"
+                    }
+                }
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "minLength": 3,
+                    "pattern": "^[a-z]+$"
+                },
+                "power": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "maximum": 100,
+                    "exclusiveMaximum": true
+                },
+                "runes": {
+                    "type": "array",
+                    "maxItems": 3,
+                    "items": {
+                        "$ref": "#/definitions/rune"
+                    }
+                },
+                "tags": {
+                    "type": "array",
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "rune": {
+            "properties": {
+                "glyph": {
+                    "type": "string",
+                    "maxLength": 1
+                }
+            }
+        }
+    },
+    "properties": {
+        "spell": {
+            "$ref": "#/definitions/spell"
+        }
+    }
+}`
+
+func TestTemplateTypesWithValidation(t *testing.T) {
+	schema := getSchemaString(t, constrainedSpellsSchema)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx := &Context{
+		Prgm:                "dispel",
+		PkgName:             "handler",
+		Routes:              routes,
+		HandlerReceiverType: "*App",
+	}
+
+	expectedOut, err := format.Source([]byte(fmt.Sprintf(`// generated by %s; DO NOT EDIT
+
+package %s
+
+import (
+    "regexp"
+    "strconv"
+    "unicode/utf8"
+)
+
+// Rune represents the data structure sent/received on the following routes:
+//
+type Rune struct {
+    Glyph *string `+"`"+`json:"glyph,omitempty"`+"`"+`
+}
+
+// Validate checks the Rune against the constraints of its JSON Schema.
+func (r *Rune) Validate() error {
+    var errs ValidationErrors
+    if r.Glyph != nil {
+        if utf8.RuneCountInString(*r.Glyph) > 1 {
+            errs = append(errs, ValidationError{Field: "glyph", Msg: "must be at most 1 characters long"})
+        }
+    }
+    if len(errs) > 0 {
+        return errs
+    }
+    return nil
+}
+
+// Spell represents the data structure sent/received on the following routes:
+//
+//  * Request body of POST /spells
+type Spell struct {
+    Name string `+"`"+`json:"name"`+"`"+`
+    Power int `+"`"+`json:"power"`+"`"+`
+    Runes []Rune `+"`"+`json:"runes,omitempty"`+"`"+`
+    Tags []string `+"`"+`json:"tags,omitempty"`+"`"+`
+}
+
+var spellNamePattern = regexp.MustCompile("^[a-z]+$")
+
+// Validate checks the Spell against the constraints of its JSON Schema.
+func (s *Spell) Validate() error {
+    var errs ValidationErrors
+    if utf8.RuneCountInString(s.Name) < 3 {
+        errs = append(errs, ValidationError{Field: "name", Msg: "must be at least 3 characters long"})
+    }
+    if !spellNamePattern.MatchString(s.Name) {
+        errs = append(errs, ValidationError{Field: "name", Msg: "must match the pattern ^[a-z]+$"})
+    }
+    if s.Power < 0 {
+        errs = append(errs, ValidationError{Field: "power", Msg: "must be greater than or equal to 0"})
+    }
+    if s.Power >= 100 {
+        errs = append(errs, ValidationError{Field: "power", Msg: "must be less than 100"})
+    }
+    if s.Runes != nil {
+        if len(s.Runes) > 3 {
+            errs = append(errs, ValidationError{Field: "runes", Msg: "must have at most 3 items"})
+        }
+        for idx := range s.Runes {
+            if err := s.Runes[idx].Validate(); err != nil {
+                errs = errs.appendPrefixed("runes["+strconv.Itoa(idx)+"]", err)
+            }
+        }
+    }
+    if s.Tags != nil {
+        {
+            seen := make(map[string]bool)
+            for _, item := range s.Tags {
+                if seen[item] {
+                    errs = append(errs, ValidationError{Field: "tags", Msg: "must not contain duplicate items"})
+                    break
+                }
+                seen[item] = true
+            }
+        }
+    }
+    if len(errs) > 0 {
+        return errs
+    }
+    return nil
+}
+
+%s
+`, ctx.Prgm, ctx.PkgName, validationErrorsDecl)))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tmpl, err := NewTemplate(sp, typesTmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Generate(&buf, ctx); err != nil {
+		t.Error(err)
+		return
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Log(buf.String())
+		t.Error(err)
+		return
+	}
+	if string(expectedOut) != string(out) {
+		t.Errorf("expected %#v, got %#v", string(expectedOut), string(out))
+		return
+	}
+}
+
+func TestTemplateHandlersWithValidation(t *testing.T) {
+	schema := getSchemaString(t, constrainedSpellsSchema)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx := &Context{
+		Prgm:                "dispel",
+		PkgName:             "handler",
+		Routes:              routes,
+		HandlerReceiverType: "*App",
+	}
+
+	tmpl, err := NewTemplate(sp, handlersTmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Generate(&buf, ctx); err != nil {
+		t.Error(err)
+		return
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Log(buf.String())
+		t.Error(err)
+		return
+	}
+	expectedCall := `			if err := hd.Decode(w, r, &vreq); err != nil {
+				return http.StatusBadRequest, err
+			}
+			if err := vreq.Validate(); err != nil {
+				return http.StatusUnprocessableEntity, err
+			}
+`
+	if !bytes.Contains(out, []byte(expectedCall)) {
+		t.Errorf("expected %q in %s", expectedCall, out)
+	}
+}
+
+func TestTypeNeedsValidation(t *testing.T) {
+	schema := getSchemaString(t, constrainedSpellsSchema)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	routes, err := sp.ParseRoutes()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tmpl, err := NewTemplate(sp, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tests := []struct {
+		ExistingTypes []string
+		Expected      map[string]bool
+	}{
+		{
+			Expected: map[string]bool{"Spell": true, "Rune": true},
+		},
+		// Validation of a type defined by the user isn't generated.
+		{
+			ExistingTypes: []string{"Rune"},
+			Expected:      map[string]bool{"Spell": true, "Rune": false},
+		},
+	}
+	for i, test := range tests {
+		tmpl.ctx = &Context{Schema: sp, Routes: routes, ExistingTypes: test.ExistingTypes}
+		for _, jtn := range routes.JSONNamedTypes() {
+			if v := tmpl.TypeNeedsValidation(jtn); v != test.Expected[jtn.TypeName()] {
+				t.Errorf("%d: %s: expected %v, got %v", i, jtn.TypeName(), test.Expected[jtn.TypeName()], v)
+			}
+		}
+	}
+}
+
+func TestInvalidPattern(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": "object",
+    "properties": {
+        "name": {
+            "type": "string",
+            "pattern": "^[a-z+$"
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	_, err := sp.JSONTypeFromSchema("Spell", schema, "")
+	if _, ok := err.(InvalidSchemaError); !ok {
+		t.Errorf("expected an InvalidSchemaError, got %#v", err)
+	}
+}
+
+func TestPrintValidateFuncWithFormats(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": "object",
+    "required": ["id"],
+    "properties": {
+        "id": {
+            "type": "string",
+            "format": "uuid"
+        },
+        "home": {
+            "type": "string",
+            "format": "uri"
+        },
+        "since": {
+            "type": "string",
+            "format": "date"
+        },
+        "checksum": {
+            "type": "string",
+            "format": "byte",
+            "maxLength": 64
+        }
+    }
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	jt, err := sp.JSONTypeFromSchema("Spell", schema, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tmpl, err := NewTemplate(sp, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tmpl.ctx = &Context{Schema: sp}
+
+	src := tmpl.PrintValidateFunc(jt)
+	for _, expected := range []string{
+		`var spellIDFormat = regexp.MustCompile(`,
+		`if !spellIDFormat.MatchString(s.ID) {`,
+		`if u, err := url.Parse(*s.Home); err != nil || !u.IsAbs() {`,
+		`if _, err := time.Parse("2006-01-02", *s.Since); err != nil {`,
+	} {
+		if !strings.Contains(src, expected) {
+			t.Errorf("expected %q in %s", expected, src)
+		}
+	}
+	if strings.Contains(src, "Checksum") {
+		t.Errorf("expected no check of the bytes in %s", src)
+	}
+}
+
+func TestTemplateTypesWithUnionValidation(t *testing.T) {
+	out := generateTemplate(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "weapon": {
+            "required": ["damage"],
+            "properties": {
+                "damage": {"type": "integer", "minimum": 0}
+            }
+        },
+        "armor": {
+            "properties": {
+                "defense": {"type": "integer"}
+            }
+        },
+        "item": {
+            "oneOf": [
+                {"$ref": "#/definitions/weapon"},
+                {"$ref": "#/definitions/armor"}
+            ]
+        },
+        "server": {
+            "properties": {
+                "items": {"type": "array", "items": {"$ref": "#/definitions/item"}},
+                "hand": {"$ref": "#/definitions/item"}
+            },
+            "links": [
+                {"href": "/servers", "method": "POST", "rel": "create", "schema": {"$ref": "#/definitions/server"}}
+            ]
+        }
+    },
+    "properties": {
+        "server": {"$ref": "#/definitions/server"}
+    }
+}`, typesTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	for _, expected := range []string{
+		"func (i *Item) Validate() error {\n\tswitch {\n\tcase i.Weapon != nil:\n\t\treturn i.Weapon.Validate()\n\t}\n\treturn nil\n}",
+		"\tif s.Items != nil {\n\t\tfor idx := range s.Items {\n\t\t\tif err := s.Items[idx].Validate(); err != nil {\n\t\t\t\terrs = errs.appendPrefixed(\"items[\"+strconv.Itoa(idx)+\"]\", err)",
+		"\tif s.Hand != nil {\n\t\tif err := s.Hand.Validate(); err != nil {\n\t\t\terrs = errs.appendPrefixed(\"hand\", err)",
+	} {
+		if !bytes.Contains(out, []byte(expected)) {
+			t.Errorf("expected %s in\n%s", expected, out)
+		}
+	}
+	if bytes.Contains(out, []byte("a.Validate()")) {
+		t.Errorf("expected no validation of the armor in\n%s", out)
+	}
+}
+
+func TestPrintValidateFuncWithItemsAndValues(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": "object",
+    "required": ["tags"],
+    "properties": {
+        "tags": {
+            "type": "array",
+            "items": {"type": "string", "maxLength": 8, "pattern": "^[a-z]+$"}
+        },
+        "grid": {
+            "type": "array",
+            "items": {"type": "array", "items": {"type": "integer", "minimum": 0}}
+        },
+        "scores": {
+            "type": "object",
+            "additionalProperties": {"type": "number", "minimum": 0}
+        },
+        "tokens": {
+            "type": "array",
+            "items": {"type": ["string", "null"], "minLength": 1}
+        }
+    },
+    "additionalProperties": {"type": "integer", "maximum": 10}
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	jt, err := sp.JSONTypeFromSchema("Spell", schema, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tmpl, err := NewTemplate(sp, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tmpl.ctx = &Context{Schema: sp}
+
+	src := tmpl.PrintValidateFunc(jt)
+	if _, err := format.Source([]byte(src)); err != nil {
+		t.Log(src)
+		t.Error(err)
+		return
+	}
+	for _, expected := range []string{
+		"var spellTagsItemPattern = regexp.MustCompile(\"^[a-z]+$\")\n",
+		"for idx := range s.Tags {\nif utf8.RuneCountInString(s.Tags[idx]) > 8 {\nerrs = append(errs, ValidationError{Field: \"tags[\"+strconv.Itoa(idx)+\"]\", Msg: \"must be at most 8 characters long\"})\n}\n",
+		"if !spellTagsItemPattern.MatchString(s.Tags[idx]) {\n",
+		"if s.Grid != nil {\nfor idx := range s.Grid {\nfor idx2 := range s.Grid[idx] {\nif s.Grid[idx][idx2] < 0 {\nerrs = append(errs, ValidationError{Field: \"grid[\"+strconv.Itoa(idx)+\"][\"+strconv.Itoa(idx2)+\"]\", ",
+		"if s.Scores != nil {\nfor key, item := range s.Scores {\nif item < 0 {\nerrs = append(errs, ValidationError{Field: \"scores.\"+key, ",
+		"for idx := range s.Tokens {\nif s.Tokens[idx] != nil {\nif utf8.RuneCountInString(*s.Tokens[idx]) < 1 {\n",
+		"for key, item := range s.AdditionalProperties {\nif item > 10 {\nerrs = append(errs, ValidationError{Field: key, Msg: \"must be less than or equal to 10\"})\n",
+	} {
+		if !strings.Contains(src, expected) {
+			t.Errorf("expected %q in %s", expected, src)
+		}
+	}
+}
+
+func TestPrintValidateFuncWithPatternProperties(t *testing.T) {
+	schema := getSchemaString(t, `{
+    "type": "object",
+    "properties": {
+        "limits": {
+            "type": "object",
+            "patternProperties": {"^max": {"type": "integer", "maximum": 10}}
+        }
+    },
+    "patternProperties": {"^x-": {"type": "string", "minLength": 1}}
+}`)
+	if t.Failed() {
+		return
+	}
+	sp := &SchemaParser{RootSchema: schema}
+	jt, err := sp.JSONTypeFromSchema("Spell", schema, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tmpl, err := NewTemplate(sp, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tmpl.ctx = &Context{Schema: sp}
+
+	src := tmpl.PrintValidateFunc(jt)
+	if _, err := format.Source([]byte(src)); err != nil {
+		t.Log(src)
+		t.Error(err)
+		return
+	}
+	// The values whose key doesn't match the pattern aren't described by its schema.
+	for _, expected := range []string{
+		"var spellLimitsKeyPattern = regexp.MustCompile(\"^max\")\n",
+		"var spellAdditionalPropertiesKeyPattern = regexp.MustCompile(\"^x-\")\n",
+		"for key, item := range s.Limits {\nif spellLimitsKeyPattern.MatchString(key) {\nif item > 10 {\n",
+		"for key, item := range s.AdditionalProperties {\nif spellAdditionalPropertiesKeyPattern.MatchString(key) {\nif utf8.RuneCountInString(item) < 1 {\n",
+	} {
+		if !strings.Contains(src, expected) {
+			t.Errorf("expected %q in %s", expected, src)
+		}
+	}
+}
+
+// typeCheck type-checks the generated Go source file src, importing the standard library from source.
+func typeCheck(t *testing.T, src []byte) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "dispel_types.go", src, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("handler", fset, []*ast.File{f}, nil); err != nil {
+		t.Logf("%s", src)
+		t.Error(err)
+	}
+}
+
+func TestTemplateTypesWithRecursiveItemsValidation(t *testing.T) {
+	out := generateTemplate(t, `{
+    "$schema": "http://json-schema.org/draft-04/hyper-schema",
+    "type": "object",
+    "definitions": {
+        "node": {
+            "properties": {
+                "name": {"type": "string", "minLength": 1},
+                "children": {"type": "array", "items": {"$ref": "#/definitions/node"}},
+                "byName": {"type": "object", "additionalProperties": {"$ref": "#/definitions/node"}}
+            },
+            "links": [
+                {"href": "/nodes", "method": "POST", "rel": "create", "schema": {"$ref": "#/definitions/node"}}
+            ]
+        }
+    },
+    "properties": {
+        "node": {"$ref": "#/definitions/node"}
+    }
+}`, typesTmpl, nil)
+	if t.Failed() {
+		return
+	}
+	// The items and values are structs, not pointers like the recursive fields.
+	for _, expected := range []string{
+		"\t\tfor idx := range n.Children {\n\t\t\tif err := n.Children[idx].Validate(); err != nil {\n",
+		"\t\tfor key, item := range n.ByName {\n\t\t\tif err := item.Validate(); err != nil {\n",
+	} {
+		if !bytes.Contains(out, []byte(expected)) {
+			t.Errorf("expected %s in\n%s", expected, out)
+		}
+	}
+	typeCheck(t, out)
+}
@@ -2,4 +2,4 @@ package dispel
 
 // Version represents the version of the API generated by dispel.
 // Any visible change makes this version bump by 1.
-const Version = 6
+const Version = 26